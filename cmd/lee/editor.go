@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// control keys
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// editor reads lines from a terminal in raw mode with emacs style key
+// bindings and history. The preview of the line, if set, is shown below it
+// and updated on every key. Lines longer than the terminal scroll
+// horizontally, so the line and the preview always take one row each.
+type editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	prompt  string
+	width   int
+	history []string
+	preview func(line string) string
+
+	buf    []rune
+	cursor int
+	// offset is the first rune of the line shown after the prompt
+	offset int
+	// hist is the history entry being edited, len(history) is the new line
+	hist  int
+	saved []rune
+}
+
+func newEditor(in *bufio.Reader, out io.Writer, prompt string) *editor {
+	return &editor{in: in, out: out, prompt: prompt, width: 80}
+}
+
+// readLine returns io.EOF on ctrl-d on an empty line
+func (e *editor) readLine() (string, error) {
+	e.buf, e.cursor, e.offset = nil, 0, 0
+	e.hist, e.saved = len(e.history), nil
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			e.finish()
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			e.finish()
+			line := string(e.buf)
+			if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+				e.history = append(e.history, line)
+			}
+			return line, nil
+		case keyCtrlC:
+			e.buf, e.cursor = nil, 0
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				e.finish()
+				return "", io.EOF
+			}
+			e.deleteAt(e.cursor)
+		case keyBackspace, keyDelete:
+			if e.cursor > 0 {
+				e.cursor--
+				e.deleteAt(e.cursor)
+			}
+		case keyCtrlA:
+			e.cursor = 0
+		case keyCtrlE:
+			e.cursor = len(e.buf)
+		case keyCtrlB:
+			e.move(-1)
+		case keyCtrlF:
+			e.move(1)
+		case keyCtrlK:
+			e.buf = e.buf[:e.cursor]
+		case keyCtrlU:
+			e.buf = append([]rune{}, e.buf[e.cursor:]...)
+			e.cursor = 0
+		case keyCtrlW:
+			e.deleteWord()
+		case keyCtrlP:
+			e.browse(-1)
+		case keyCtrlN:
+			e.browse(1)
+		case keyEscape:
+			if err := e.escape(); err != nil {
+				e.finish()
+				return "", err
+			}
+		default:
+			if r >= ' ' {
+				e.insert(r)
+			}
+		}
+		e.refresh()
+	}
+}
+
+// escape handles the ansi sequences of arrow, home, end and delete keys
+func (e *editor) escape() error {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r != '[' && r != 'O' {
+		return nil
+	}
+
+	var params []rune
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		params = append(params, r)
+	}
+
+	switch {
+	case r == 'A':
+		e.browse(-1)
+	case r == 'B':
+		e.browse(1)
+	case r == 'C':
+		e.move(1)
+	case r == 'D':
+		e.move(-1)
+	case r == 'H' || r == '~' && (string(params) == "1" || string(params) == "7"):
+		e.cursor = 0
+	case r == 'F' || r == '~' && (string(params) == "4" || string(params) == "8"):
+		e.cursor = len(e.buf)
+	case r == '~' && string(params) == "3":
+		e.deleteAt(e.cursor)
+	}
+	return nil
+}
+
+func (e *editor) insert(r rune) {
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.cursor+1:], e.buf[e.cursor:])
+	e.buf[e.cursor] = r
+	e.cursor++
+}
+
+func (e *editor) deleteAt(idx int) {
+	if idx < len(e.buf) {
+		e.buf = append(e.buf[:idx], e.buf[idx+1:]...)
+	}
+}
+
+func (e *editor) deleteWord() {
+	start := e.cursor
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	e.buf = append(e.buf[:start], e.buf[e.cursor:]...)
+	e.cursor = start
+}
+
+func (e *editor) move(delta int) {
+	e.cursor += delta
+	if e.cursor < 0 {
+		e.cursor = 0
+	}
+	if e.cursor > len(e.buf) {
+		e.cursor = len(e.buf)
+	}
+}
+
+// browse replaces the line with an older or a newer history entry, the
+// new line is kept while browsing
+func (e *editor) browse(delta int) {
+	next := e.hist + delta
+	if next < 0 || next > len(e.history) {
+		return
+	}
+	if e.hist == len(e.history) {
+		e.saved = e.buf
+	}
+	e.hist = next
+	if next == len(e.history) {
+		e.buf = e.saved
+	} else {
+		e.buf = []rune(e.history[next])
+	}
+	e.cursor = len(e.buf)
+}
+
+func (e *editor) truncate(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) >= e.width {
+		return string(runes[:e.width-1])
+	}
+	return s
+}
+
+// scroll moves the offset to keep the cursor visible and returns the
+// number of columns left for the line, the last column is kept free so
+// the terminal never wraps
+func (e *editor) scroll() int {
+	cols := e.width - 1 - len([]rune(e.prompt))
+	if cols < 1 {
+		cols = 1
+	}
+	// a shrinking line doesn't leave free columns behind
+	if e.offset > len(e.buf)-cols {
+		e.offset = len(e.buf) - cols
+		if e.offset < 0 {
+			e.offset = 0
+		}
+	}
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	}
+	if e.cursor > e.offset+cols {
+		e.offset = e.cursor - cols
+	}
+	return cols
+}
+
+// refresh redraws the visible part of the line and the preview below it
+func (e *editor) refresh() {
+	end := e.offset + e.scroll()
+	if end > len(e.buf) {
+		end = len(e.buf)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\r\033[K" + e.prompt + string(e.buf[e.offset:end]))
+	if e.preview != nil {
+		sb.WriteString("\r\n\033[K" + e.truncate(e.preview(string(e.buf))) + "\033[A")
+	}
+	sb.WriteString("\r")
+	if col := len([]rune(e.prompt)) + e.cursor - e.offset; col > 0 {
+		fmt.Fprintf(&sb, "\033[%dC", col)
+	}
+	_, _ = io.WriteString(e.out, sb.String())
+}
+
+// finish leaves the cursor at the start of a clean line below the edited
+// one
+func (e *editor) finish() {
+	_, _ = io.WriteString(e.out, "\r\n\033[K")
+}
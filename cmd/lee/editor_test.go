@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	input := strings.Join([]string{
+		"a = 1\r",
+		// backspace, left four times, insert
+		"a = 123\x7f\x1b[D\x1b[D\x1b[D\x1b[D>\r",
+		// up arrow recalls the previous line, then edit it at home
+		"\x1b[A\x01\x1b[3~b\r",
+		// ctrl-u kills the line, ctrl-w the last word
+		"junk\x15x = 1 yy\x17\r",
+		// browse up and back down to the new line
+		"new\x10\x10\x0e\x0e\r",
+		"\x04",
+	}, "")
+
+	ed := newEditor(bufio.NewReader(strings.NewReader(input)), io.Discard, "> ")
+	var previews []string
+	ed.preview = func(line string) string {
+		previews = append(previews, line)
+		return strings.ToUpper(line)
+	}
+
+	expected := []string{"a = 1", "a >= 12", "b >= 12", "x = 1 ", "new"}
+	for _, exp := range expected {
+		line, err := ed.readLine()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if line != exp {
+			t.Errorf("got %q, expected %q", line, exp)
+		}
+	}
+	if _, err := ed.readLine(); err != io.EOF {
+		t.Errorf("ctrl-d on an empty line must end the input, got %v", err)
+	}
+
+	if strings.Join(ed.history, "|") != "a = 1|a >= 12|b >= 12|x = 1 |new" {
+		t.Errorf("invalid history %q", ed.history)
+	}
+	if len(previews) == 0 || previews[len(previews)-1] != "" {
+		t.Errorf("preview must be refreshed on every key, got %q", previews)
+	}
+}
+
+func TestEditorScroll(t *testing.T) {
+	cases := []struct {
+		keys string
+		// the last redraw of the line before enter
+		output string
+	}{
+		{"abcdefghij", "> defghij\r\033[9C"},
+		// ctrl-a scrolls back to the start
+		{"abcdefghij\x01", "> abcdefg\r\033[2C"},
+		// moving left within the visible part doesn't scroll
+		{"abcdefghij\x01\x05\x1b[D\x1b[D", "> defghij\r\033[7C"},
+		// ctrl-k shrinks the line, it fills the freed columns
+		{"abcdefghij\x02\x02\x0b", "> bcdefgh\r\033[9C"},
+	}
+	for i, tc := range cases {
+		var out bytes.Buffer
+		ed := newEditor(bufio.NewReader(strings.NewReader(tc.keys+"\r")), &out, "> ")
+		// seven columns are left for the line after the prompt
+		ed.width = 10
+		if _, err := ed.readLine(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		redraws := strings.Split(strings.TrimSuffix(out.String(), "\r\n\033[K"), "\r\033[K")
+		if last := redraws[len(redraws)-1]; last != tc.output {
+			t.Errorf("invalid output in case %d, got %q, expected %q", i+1, last, tc.output)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/vatsimnerd/lee/document"
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+// exit codes of lee filter, the same as grep ones
+const (
+	exitMatch   = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
+
+// exprCSVNumber matches numbers without leading zeros, cells like 0123
+// are codes rather than numbers
+var exprCSVNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+type filter struct {
+	program *parser.Program[map[string]any]
+	invert  bool
+	count   bool
+	fields  []string
+	// csvStrings keeps every CSV cell a string
+	csvStrings bool
+
+	out      io.Writer
+	csvOut   *csv.Writer
+	header   []string
+	selected int
+}
+
+func runFilter(args []string) int {
+	fs := flag.NewFlagSet("filter", flag.ContinueOnError)
+	count := fs.Bool("count", false, "print the number of selected records instead of the records")
+	invert := fs.Bool("invert", false, "select the records which don't match")
+	fields := fs.String("fields", "", "print only the comma separated `paths` of the selected records")
+	csvInput := fs.Bool("csv", false, "read CSV with a header row instead of JSON")
+	csvStrings := fs.Bool("csv-strings", false, "keep CSV cells looking like numbers as strings, i.e. codes like transponder 7000")
+	macrosFile := fs.String("macros", "", macrosUsage)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: lee filter [flags] expression [file ...]\n\n")
+		fmt.Fprintf(fs.Output(), "Prints the records of JSON Lines, JSON arrays or CSV files matching the\n")
+		fmt.Fprintf(fs.Output(), "expression, reads stdin if no files are given. Identifiers are dotted\n")
+		fmt.Fprintf(fs.Output(), "paths into the records, numeric parts index arrays. Exits with 0 if any\n")
+		fmt.Fprintf(fs.Output(), "record was selected, 1 if none was and 2 on errors.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	macros, err := loadMacros(*macrosFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lee filter: %v\n", err)
+		return exitError
+	}
+	f, err := newFilter(fs.Arg(0), macros, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lee filter: %v\n", err)
+		return exitError
+	}
+	f.invert, f.count, f.csvStrings = *invert, *count, *csvStrings
+	if *fields != "" {
+		f.fields = strings.Split(*fields, ",")
+	}
+
+	read := f.readJSON
+	if *csvInput {
+		read = f.readCSV
+	}
+
+	failed := false
+	if fs.NArg() == 1 {
+		if err := read(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "lee filter: <stdin>: %v\n", err)
+			failed = true
+		}
+	}
+	for _, filename := range fs.Args()[1:] {
+		if err := readFile(filename, read); err != nil {
+			fmt.Fprintf(os.Stderr, "lee filter: %s: %v\n", filename, err)
+			failed = true
+		}
+	}
+
+	if err := f.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "lee filter: %v\n", err)
+		failed = true
+	}
+	if f.count {
+		fmt.Fprintln(out, f.selected)
+	}
+
+	switch {
+	case failed:
+		return exitError
+	case f.selected == 0:
+		return exitNoMatch
+	default:
+		return exitMatch
+	}
+}
+
+func readFile(filename string, read func(io.Reader) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return read(file)
+}
+
+// compileDocument compiles the expression for decoded JSON documents,
+// the macros may be nil
+func compileDocument(src string, macros *parser.Macros) (*parser.Program[map[string]any], error) {
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := parser.ParseWithMacros[map[string]any](tokens, macros)
+	if err != nil {
+		return nil, err
+	}
+	program, err := expr.Compile(document.Compiler(document.Options{}))
+	if err != nil {
+		return nil, err
+	}
+	// the command line has no way to bind them, such a filter never matches
+	if params := program.Params(); params != nil {
+		return nil, fmt.Errorf("expression has unbound parameters %s", strings.Join(params, ", "))
+	}
+	return program, nil
+}
+
+func newFilter(src string, macros *parser.Macros, out io.Writer) (*filter, error) {
+	program, err := compileDocument(src, macros)
+	if err != nil {
+		return nil, err
+	}
+	return &filter{program: program, out: out}, nil
+}
+
+// readJSON reads a JSON array of records or a sequence of records, i.e.
+// JSON Lines
+func (f *filter) readJSON(in io.Reader) error {
+	br := bufio.NewReader(in)
+	array := false
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			_, _ = br.ReadByte()
+			continue
+		}
+		array = b[0] == '['
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for n := 1; !array || dec.More(); n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF && !array {
+				return nil
+			}
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+
+		rd := json.NewDecoder(bytes.NewReader(raw))
+		rd.UseNumber()
+		var doc any
+		if err := rd.Decode(&doc); err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if err := f.writeJSON(doc, raw); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	// only whitespace may follow the array
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the array of records")
+	}
+	return nil
+}
+
+// readCSV reads rows with the field names in the header row, dotted names
+// make nested fields. Cells looking like numbers without leading zeros are
+// numbers unless csvStrings is set, the rest are strings.
+func (f *filter) readCSV(in io.Reader) error {
+	r := csv.NewReader(in)
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		doc := make(map[string]any, len(header))
+		for i, name := range header {
+			var value any = row[i]
+			if !f.csvStrings && exprCSVNumber.MatchString(row[i]) {
+				value = json.Number(row[i])
+			}
+			setPath(doc, name, value)
+		}
+		if err := f.writeCSV(doc, header, row); err != nil {
+			return err
+		}
+	}
+}
+
+func setPath(doc map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := doc[key].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			doc[key] = next
+		}
+		doc = next
+	}
+	doc[keys[len(keys)-1]] = value
+}
+
+// selects evaluates the document and counts the selected ones, records
+// which aren't objects have no fields
+func (f *filter) selects(doc any) bool {
+	obj, _ := doc.(map[string]any)
+	if f.program.Evaluate(obj) == f.invert {
+		return false
+	}
+	f.selected++
+	return !f.count
+}
+
+func (f *filter) writeJSON(doc any, raw json.RawMessage) error {
+	if !f.selects(doc) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if f.fields == nil {
+		if err := json.Compact(&buf, raw); err != nil {
+			return err
+		}
+	} else {
+		buf.WriteByte('{')
+		for i, path := range f.fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			value, _ := document.Lookup(doc, path)
+			key, _ := json.Marshal(path)
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(data)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+	_, err := f.out.Write(buf.Bytes())
+	return err
+}
+
+func (f *filter) writeCSV(doc any, header []string, row []string) error {
+	if !f.selects(doc) {
+		return nil
+	}
+
+	if f.fields != nil {
+		header = f.fields
+		row = make([]string, len(f.fields))
+		for i, path := range f.fields {
+			value, _ := document.Lookup(doc, path)
+			row[i] = cell(value)
+		}
+	}
+
+	if f.csvOut == nil {
+		f.csvOut = csv.NewWriter(f.out)
+	}
+	// every file may have its own columns
+	if strings.Join(header, "\x00") != strings.Join(f.header, "\x00") {
+		if err := f.csvOut.Write(header); err != nil {
+			return err
+		}
+		f.header = header
+	}
+	return f.csvOut.Write(row)
+}
+
+func cell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func (f *filter) flush() error {
+	if f.csvOut == nil {
+		return nil
+	}
+	f.csvOut.Flush()
+	return f.csvOut.Error()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testJSONLines = `{"callsign": "BAW1", "altitude": 35000, "flight_plan": {"arrival": "EGLL"}, "legs": ["EGLL", "KJFK"]}
+{"callsign": "AFR2", "altitude": 1000, "flight_plan": {"arrival": "LFPG"}}
+`
+	testJSONArray = `[
+  {"callsign": "BAW1", "altitude": 35000.0},
+  {"callsign": "AFR2", "altitude": 1000}
+]`
+	testCSV = "callsign,altitude,flight_plan.arrival\nBAW1,35000,EGLL\nAFR2,1000,LFPG\n"
+	// transponder codes look like numbers
+	testCSVCodes = "callsign,transponder\nBAW1,7000\nAFR2,0123\n"
+)
+
+type filterCase struct {
+	expr       string
+	input      string
+	csv        bool
+	csvStrings bool
+	invert     bool
+	fields     []string
+	output     string
+}
+
+var filterCases = []filterCase{
+	{
+		expr:   `flight_plan.arrival =~ "^EG"`,
+		input:  testJSONLines,
+		output: `{"callsign":"BAW1","altitude":35000,"flight_plan":{"arrival":"EGLL"},"legs":["EGLL","KJFK"]}` + "\n",
+	},
+	{
+		expr:   `legs.1 = "KJFK"`,
+		input:  testJSONLines,
+		fields: []string{"callsign", "legs.0", "missing"},
+		output: `{"callsign":"BAW1","legs.0":"EGLL","missing":null}` + "\n",
+	},
+	{
+		expr:   `altitude > 5000`,
+		input:  testJSONArray,
+		invert: true,
+		output: `{"callsign":"AFR2","altitude":1000}` + "\n",
+	},
+	{
+		expr:   `altitude = 35000`,
+		input:  testJSONArray,
+		output: `{"callsign":"BAW1","altitude":35000.0}` + "\n",
+	},
+	{
+		expr:   `altitude < 5000 or flight_plan.arrival = "EGLL"`,
+		input:  testCSV,
+		csv:    true,
+		output: testCSV,
+	},
+	{
+		expr:   `flight_plan.arrival = "LFPG"`,
+		input:  testCSV,
+		csv:    true,
+		fields: []string{"altitude", "callsign"},
+		output: "altitude,callsign\n1000,AFR2\n",
+	},
+	{
+		expr:   `transponder = "0123"`,
+		input:  testCSVCodes,
+		csv:    true,
+		output: "callsign,transponder\nAFR2,0123\n",
+	},
+	{
+		expr:       `transponder = "7000"`,
+		input:      testCSVCodes,
+		csv:        true,
+		csvStrings: true,
+		output:     "callsign,transponder\nBAW1,7000\n",
+	},
+	{
+		expr:   `transponder = 7000`,
+		input:  testCSVCodes,
+		csv:    true,
+		output: "callsign,transponder\nBAW1,7000\n",
+	},
+	{
+		expr:   `callsign = "DLH3"`,
+		input:  testJSONLines,
+		output: "",
+	},
+}
+
+func TestFilter(t *testing.T) {
+	for i, tc := range filterCases {
+		var out bytes.Buffer
+		f, err := newFilter(tc.expr, nil, &out)
+		if err != nil {
+			t.Fatalf("unexpected error in case %d: %v", i+1, err)
+		}
+		f.invert, f.fields, f.csvStrings = tc.invert, tc.fields, tc.csvStrings
+
+		read := f.readJSON
+		if tc.csv {
+			read = f.readCSV
+		}
+		if err := read(strings.NewReader(tc.input)); err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if err := f.flush(); err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+		}
+		if out.String() != tc.output {
+			t.Errorf("invalid output in case %d, got\n%s\nexpected\n%s", i+1, out.String(), tc.output)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	if _, err := newFilter(`a = `, nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("invalid expressions must fail")
+	}
+	if _, err := newFilter(`a = 1) or b = 2`, nil, &bytes.Buffer{}); err == nil || err.Error() != "unexpected token ) at line 1 pos 6" {
+		t.Errorf("got %v, expected the unexpected token error", err)
+	}
+	if _, err := newFilter(`a = $x or b = :y`, nil, &bytes.Buffer{}); err == nil || err.Error() != "expression has unbound parameters x, y" {
+		t.Errorf("got %v, expected the unbound parameters error", err)
+	}
+
+	f, err := newFilter(`a = 1`, nil, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		`{"a": 1} {"a": `:       "record 2: unexpected EOF",
+		`[{"a": 1}, {"a"`:       "record 2: unexpected EOF",
+		`[{"a": 1}] [{"a": 1}]`: "unexpected data after the array of records",
+		`[{"a": 1}] x`:          "unexpected data after the array of records",
+	}
+	for input, exp := range cases {
+		if err := f.readJSON(strings.NewReader(input)); err == nil || err.Error() != exp {
+			t.Errorf("%s got %v, expected %s", input, err, exp)
+		}
+	}
+	if err := f.readCSV(strings.NewReader("a,b\n1\n")); err == nil {
+		t.Errorf("rows with missing cells must fail")
+	}
+}
+
+func TestFilterMacros(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "macros.json")
+	defs := `{"uk": "flight_plan.arrival =~ '^EG'", "uk_high": "@uk and altitude > 10000"}`
+	if err := os.WriteFile(filename, []byte(defs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	macros, err := loadMacros(filename)
+	if err != nil {
+		t.Fatalf("unexpected error loading macros: %v", err)
+	}
+
+	var out bytes.Buffer
+	f, err := newFilter(`@uk_high`, macros, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.readJSON(strings.NewReader(testJSONLines)); err != nil {
+		t.Fatal(err)
+	}
+	if f.selected != 1 || !strings.HasPrefix(out.String(), `{"callsign":"BAW1"`) {
+		t.Errorf("got %d records\n%s", f.selected, out.String())
+	}
+
+	if _, err := newFilter(`@uk`, nil, &out); err == nil || err.Error() != "unknown reference @uk at line 1 pos 1" {
+		t.Errorf("got %v, expected the unknown reference error", err)
+	}
+	if err := os.WriteFile(filename, []byte(`{"bad name": "a = 1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadMacros(filename); err == nil {
+		t.Errorf("invalid macro names must fail")
+	}
+}
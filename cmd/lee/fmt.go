@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vatsimnerd/lee/parser"
+)
+
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	width := fs.Int("width", 0, "wrap lines longer than `n` characters, 0 disables wrapping")
+	indent := fs.String("indent", "    ", "indentation of wrapped groupings")
+	write := fs.Bool("w", false, "write the result back to the source files")
+	macrosFile := fs.String("macros", "", macrosUsage)
+	expand := fs.Bool("expand", false, "print the contents of the macros instead of the references, needs -macros")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: lee fmt [flags] [file ...]\n\nReads stdin if no files are given.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	macros, err := loadMacros(*macrosFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lee fmt: %v\n", err)
+		return 2
+	}
+	opts := parser.FormatOptions{
+		MaxWidth:       *width,
+		Indent:         *indent,
+		KeepReferences: !*expand,
+		Macros:         macros,
+	}
+
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "lee fmt: cannot use -w with stdin")
+			return 2
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lee fmt: %v\n", err)
+			return 1
+		}
+		result, err := parser.Format(string(data), opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lee fmt: <stdin>: %v\n", err)
+			return 1
+		}
+		fmt.Println(result)
+		return 0
+	}
+
+	code := 0
+	for _, filename := range fs.Args() {
+		if err := fmtFile(filename, opts, *write); err != nil {
+			fmt.Fprintf(os.Stderr, "lee fmt: %s: %v\n", filename, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+func fmtFile(filename string, opts parser.FormatOptions, write bool) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	result, err := parser.Format(string(data), opts)
+	if err != nil {
+		return err
+	}
+
+	if write {
+		return os.WriteFile(filename, []byte(result+"\n"), 0644)
+	}
+	fmt.Println(result)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/vatsimnerd/lee/parser"
+)
+
+const macrosUsage = "expand @name references with the macros of the JSON `file`, an object of names and filters"
+
+// loadMacros reads macros from a JSON object mapping the names to the
+// filter sources, i.e. {"heavy": "wtc = 'H' or wtc = 'J'"}. No file gives
+// no macros.
+func loadMacros(filename string) (*parser.Macros, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var defs map[string]string
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	// the first error doesn't depend on the map order
+	sort.Strings(names)
+
+	macros := parser.NewMacros()
+	for _, name := range names {
+		if err := macros.Define(name, defs[name]); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	return macros, nil
+}
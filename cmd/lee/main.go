@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+var commands = []command{
+	{"fmt", "print filter expressions in the canonical form", runFmt},
+	{"filter", "print the JSON or CSV records matching a filter expression", runFilter},
+	{"repl", "inspect and evaluate filter expressions interactively", runRepl},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: lee <command> [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "lee: unknown command %s\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vatsimnerd/lee/document"
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+const replHelp = `Type a filter expression to see its tokens, parse tree, canonical form
+and result against the sample record. Identifiers are dotted paths into
+the sample like in lee filter. Line editing and history need a terminal
+on linux, macOS or BSD, elsewhere lines are read as typed.
+
+commands:
+  :load file     load the first record of a JSON file as the sample
+  :load {...}    load an inline JSON record as the sample
+  :explain [expr] explain the evaluation of the expression, the last one
+                 by default, against the sample
+  :help          show this help
+  :quit          exit, ctrl-d does the same`
+
+type repl struct {
+	out    io.Writer
+	macros *parser.Macros
+	sample map[string]any
+	loaded string
+	// last is the last filter which compiled
+	last *parser.Program[map[string]any]
+}
+
+func runRepl(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	sample := fs.String("sample", "", "load the first record of the JSON `file` as the sample")
+	macrosFile := fs.String("macros", "", macrosUsage)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: lee repl [flags]\n\n%s\n\n", replHelp)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	macros, err := loadMacros(*macrosFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lee repl: %v\n", err)
+		return 1
+	}
+	r := &repl{out: os.Stdout, macros: macros}
+	if *sample != "" {
+		if err := r.load(*sample); err != nil {
+			fmt.Fprintf(os.Stderr, "lee repl: %v\n", err)
+			return 1
+		}
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	fd := int(os.Stdin.Fd())
+	interactive := isTerminal(fd)
+
+	var ed *editor
+	if interactive {
+		ed = newEditor(in, os.Stdout, "lee> ")
+		ed.width = termWidth(fd)
+		ed.preview = r.preview
+		fmt.Fprintln(r.out, "lee repl, :help for help")
+	}
+
+	for {
+		var line string
+		var err error
+		if interactive {
+			line, err = readRaw(ed, fd)
+		} else {
+			line, err = in.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+		}
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lee repl: %v\n", err)
+			return 1
+		}
+
+		if r.handle(strings.TrimSpace(line)) {
+			return 0
+		}
+	}
+}
+
+// readRaw reads a line with the editor in raw mode, the rest of the repl
+// runs in the normal mode to print with plain newlines
+func readRaw(ed *editor, fd int) (string, error) {
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	return ed.readLine()
+}
+
+// handle runs a command or evaluates a filter, it returns true to quit
+func (r *repl) handle(line string) bool {
+	if line == "" {
+		return false
+	}
+	if !strings.HasPrefix(line, ":") {
+		r.evaluate(line)
+		return false
+	}
+
+	cmd, arg := line, ""
+	if idx := strings.IndexAny(line, " \t"); idx >= 0 {
+		cmd, arg = line[:idx], strings.TrimSpace(line[idx:])
+	}
+
+	switch cmd {
+	case ":quit", ":q":
+		return true
+	case ":help", ":h":
+		fmt.Fprintln(r.out, replHelp)
+	case ":load":
+		if err := r.load(arg); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	case ":explain":
+		r.explain(arg)
+	default:
+		fmt.Fprintf(r.out, "unknown command %s, :help for help\n", cmd)
+	}
+	return false
+}
+
+// load reads the sample from a file or the inline JSON
+func (r *repl) load(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("usage: :load file or :load {...}")
+	}
+
+	data, name := []byte(arg), "inline record"
+	if !strings.HasPrefix(arg, "{") {
+		var err error
+		if data, err = os.ReadFile(arg); err != nil {
+			return err
+		}
+		name = arg
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	// the first record of an array
+	if records, ok := doc.([]any); ok {
+		if len(records) == 0 {
+			return fmt.Errorf("%s: no records", name)
+		}
+		doc = records[0]
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: the record is not an object", name)
+	}
+
+	r.sample, r.loaded = obj, name
+	sample, _ := json.Marshal(doc)
+	fmt.Fprintf(r.out, "loaded %s: %s\n", name, sample)
+	return nil
+}
+
+// preview is the one line summary shown while typing
+func (r *repl) preview(line string) string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, ":") {
+		return ""
+	}
+	p, err := compileDocument(line, r.macros)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	canonical, err := p.Expression().Format(parser.FormatOptions{})
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if r.loaded == "" {
+		return canonical
+	}
+	return fmt.Sprintf("%v: %s", p.Evaluate(r.sample), canonical)
+}
+
+func (r *repl) evaluate(src string) {
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+
+	fmt.Fprintln(r.out, "tokens:")
+	for _, t := range tokens.Tokens() {
+		fmt.Fprintf(r.out, "  %-14s %-20q %d:%d\n", t.Type, t.Literal, t.Line, t.Position)
+	}
+
+	p, err := compileDocument(src, r.macros)
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+	r.last = p
+
+	var tree strings.Builder
+	renderTree(&tree, p.Expression(), "  ")
+	fmt.Fprintf(r.out, "tree:\n%s", tree.String())
+	canonical, err := p.Expression().Format(parser.FormatOptions{})
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+	fmt.Fprintf(r.out, "canonical:\n  %s\n", canonical)
+	if r.loaded == "" {
+		fmt.Fprintln(r.out, "result: no sample, :load one")
+	} else {
+		fmt.Fprintf(r.out, "result: %v\n", p.Evaluate(r.sample))
+	}
+}
+
+func (r *repl) explain(src string) {
+	p := r.last
+	if src != "" {
+		var err error
+		if p, err = compileDocument(src, r.macros); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+			return
+		}
+	}
+
+	switch {
+	case p == nil:
+		fmt.Fprintln(r.out, "error: no filter to explain")
+	case r.loaded == "":
+		fmt.Fprintln(r.out, "error: no sample, :load one")
+	default:
+		fmt.Fprintln(r.out, p.WithFields(document.Fields()).Explain(r.sample))
+	}
+}
+
+// renderTree prints the right-leaning expression chain the way the parser
+// built it
+func renderTree[T any](sb *strings.Builder, e *parser.Expression[T], indent string) {
+	sb.WriteString(indent + "expression\n")
+	indent += "  "
+
+	if c := e.Left.Condition; c != nil {
+		fmt.Fprintf(sb, "%scondition %s", indent, c.Format())
+		if tok := c.Identifier.Token; tok != nil {
+			fmt.Fprintf(sb, "  %d:%d", tok.Line, tok.Position)
+		}
+		sb.WriteString("\n")
+	} else {
+		g := e.Left.Grouping
+		if g.Reference != nil {
+			fmt.Fprintf(sb, "%sgrouping @%s\n", indent, g.Reference.Name)
+		} else {
+			sb.WriteString(indent + "grouping\n")
+		}
+		renderTree(sb, g.Expression, indent+"  ")
+	}
+
+	if e.Right != nil {
+		fmt.Fprintf(sb, "%s%s\n", indent, strings.ToLower(e.Operator.Type.String()))
+		renderTree(sb, e.Right, indent)
+	}
+}
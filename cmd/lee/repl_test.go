@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	var out bytes.Buffer
+	r := &repl{out: &out}
+
+	if r.preview(`a = `) != "error: unexpected token  at line 1 pos 4" {
+		t.Errorf("invalid preview of an incomplete filter: %s", r.preview(`a = `))
+	}
+
+	if preview := r.preview(`a = 1) or b = 2`); preview != "error: unexpected token ) at line 1 pos 6" {
+		t.Errorf("invalid preview of an unbalanced filter: %s", preview)
+	}
+
+	r.handle(`:load {"callsign": "BAW1", "fp": {"arrival": "EGLL"}, "altitude": 35000}`)
+	if preview := r.preview(`fp.arrival="EGLL"`); preview != `true: fp.arrival = "EGLL"` {
+		t.Errorf("invalid preview %s", preview)
+	}
+
+	out.Reset()
+	r.handle(`callsign = "BAW1" and (altitude < 1000)`)
+	exp := strings.Join([]string{
+		`tokens:`,
+		`  Identifier     "callsign"           1:1`,
+		`  Equals         "="                  1:10`,
+		`  String         "\"BAW1\""           1:12`,
+		`  And            "and"                1:19`,
+		`  LBrace         "("                  1:23`,
+		`  Identifier     "altitude"           1:24`,
+		`  Less           "<"                  1:33`,
+		`  Number         "1000"               1:35`,
+		`  RBrace         ")"                  1:39`,
+		`  EOF            ""                   1:40`,
+		`tree:`,
+		`  expression`,
+		`    condition callsign = "BAW1"  1:1`,
+		`    and`,
+		`    expression`,
+		`      grouping`,
+		`        expression`,
+		`          condition altitude < 1000  1:24`,
+		`canonical:`,
+		`  callsign = "BAW1" and altitude < 1000`,
+		`result: false`,
+		``,
+	}, "\n")
+	if out.String() != exp {
+		t.Errorf("invalid output, got\n%s\nexpected\n%s", out.String(), exp)
+	}
+
+	out.Reset()
+	r.handle(`:explain`)
+	if !strings.Contains(out.String(), "false    altitude < 1000  (altitude is 35000)") {
+		t.Errorf("invalid explanation\n%s", out.String())
+	}
+
+	out.Reset()
+	r.handle(`:explain a = $x`)
+	if preview := r.preview(`a = $x`); preview != "error: expression has unbound parameters x" || out.String() != preview+"\n" {
+		t.Errorf("unbound parameters must fail, got %s and %s", preview, out.String())
+	}
+
+	out.Reset()
+	r.handle(`:load missing.json`)
+	if !strings.HasPrefix(out.String(), "error: open missing.json") {
+		t.Errorf("invalid load error %s", out.String())
+	}
+	if !r.handle(`:quit`) {
+		t.Errorf(":quit must end the repl")
+	}
+}
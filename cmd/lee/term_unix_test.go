@@ -0,0 +1,28 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fd := int(f.Fd())
+	if isTerminal(fd) {
+		t.Errorf("a file is not a terminal")
+	}
+	if _, err := makeRaw(fd); err == nil {
+		t.Errorf("a file can't switch to raw mode")
+	}
+	if width := termWidth(fd); width != 80 {
+		t.Errorf("unknown width must default to 80, got %d", width)
+	}
+}
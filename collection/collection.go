@@ -0,0 +1,152 @@
+// Package collection answers queries against a snapshot of models using
+// secondary indexes on chosen fields. Field values follow the semantics of
+// the match package, so a query returns exactly the models its expression
+// compiled with match.Compiler would accept.
+package collection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+//go:generate stringer -type=IndexType -trimprefix=Index
+
+type (
+	IndexType int
+
+	// Index declares an index on a field
+	Index struct {
+		Field string
+		Type  IndexType
+	}
+
+	// Collection is an immutable snapshot of models with indexes, it's safe
+	// for concurrent use
+	Collection[T any] struct {
+		models    []T
+		accessors map[string]match.Accessor[T]
+		compile   parser.CompilationCallback[T]
+
+		hash   map[string]*hashIndex
+		sorted map[string]*sortedIndex
+		prefix map[string]*trieNode
+	}
+)
+
+const (
+	// IndexHash answers = conditions and ors of them
+	IndexHash IndexType = iota
+	// IndexSorted answers =, <, <=, > and >= conditions
+	IndexSorted
+	// IndexPrefix answers = conditions on strings and regular expressions
+	// anchored at the start with a literal prefix, i.e. callsign =~ "^AFL"
+	IndexPrefix
+)
+
+// New builds the indexes over the models, the slice is copied so changing
+// it later doesn't affect the collection
+func New[T any](models []T, accessors map[string]match.Accessor[T], indexes ...Index) (*Collection[T], error) {
+	c := &Collection[T]{
+		models:    append([]T(nil), models...),
+		accessors: accessors,
+		compile:   match.Compiler(accessors),
+		hash:      make(map[string]*hashIndex),
+		sorted:    make(map[string]*sortedIndex),
+		prefix:    make(map[string]*trieNode),
+	}
+
+	values := make(map[string][]any)
+	for _, idx := range indexes {
+		accessor, found := accessors[idx.Field]
+		if !found {
+			return nil, fmt.Errorf("can't index unknown field %s", idx.Field)
+		}
+
+		vals, found := values[idx.Field]
+		if !found {
+			vals = make([]any, len(c.models))
+			for row, model := range c.models {
+				vals[row] = accessor(model)
+			}
+			values[idx.Field] = vals
+		}
+
+		switch idx.Type {
+		case IndexHash:
+			c.hash[idx.Field] = newHashIndex(vals)
+		case IndexSorted:
+			c.sorted[idx.Field] = newSortedIndex(vals)
+		case IndexPrefix:
+			c.prefix[idx.Field] = newTrie(vals)
+		default:
+			return nil, fmt.Errorf("invalid index type %s for field %s", idx.Type, idx.Field)
+		}
+	}
+	return c, nil
+}
+
+// Len returns the number of models in the collection
+func (c *Collection[T]) Len() int {
+	return len(c.models)
+}
+
+// Query returns the models matching the expression in the snapshot order
+func (c *Collection[T]) Query(expr *parser.Expression[T]) ([]T, error) {
+	rows, err := c.QueryIndices(expr)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]T, len(rows))
+	for i, row := range rows {
+		result[i] = c.models[row]
+	}
+	return result, nil
+}
+
+// QueryIndices returns the ascending indices of the models matching the
+// expression
+func (c *Collection[T]) QueryIndices(expr *parser.Expression[T]) ([]int, error) {
+	program, err := c.check(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := c.plan(expr)
+	rows := plan.run()
+	if plan.Exact {
+		// rows may be owned by an index
+		return append([]int(nil), rows...), nil
+	}
+
+	matching := rows[:0:0]
+	for _, row := range rows {
+		if program.Evaluate(c.models[row]) {
+			matching = append(matching, row)
+		}
+	}
+	return matching, nil
+}
+
+// Plan returns the plan a query of the expression would execute
+func (c *Collection[T]) Plan(expr *parser.Expression[T]) (*Plan, error) {
+	if _, err := c.check(expr); err != nil {
+		return nil, err
+	}
+	return c.plan(expr), nil
+}
+
+// check compiles the expression, the planner relies on its conditions
+// being valid
+func (c *Collection[T]) check(expr *parser.Expression[T]) (*parser.Program[T], error) {
+	program, err := expr.Compile(c.compile)
+	if err != nil {
+		return nil, err
+	}
+	if params := program.Params(); params != nil {
+		return nil, fmt.Errorf("expression has unbound parameters %s", strings.Join(params, ", "))
+	}
+	return program, nil
+}
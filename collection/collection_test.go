@@ -0,0 +1,222 @@
+package collection
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type pilot struct {
+	Callsign string
+	Arrival  string
+	Alt      int
+	Speed    any
+}
+
+var accessors = map[string]match.Accessor[pilot]{
+	"callsign": func(p pilot) any { return p.Callsign },
+	"arrival":  func(p pilot) any { return p.Arrival },
+	"alt":      func(p pilot) any { return p.Alt },
+	"speed":    func(p pilot) any { return p.Speed },
+}
+
+var indexes = []Index{
+	{"callsign", IndexPrefix},
+	{"arrival", IndexHash},
+	{"alt", IndexSorted},
+	{"speed", IndexSorted},
+}
+
+func parse(t testing.TB, src string) *parser.Expression[pilot] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[pilot](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+var pilots = []pilot{
+	{"AFL123", "UUEE", 35000, 450},
+	{"AFL9", "UUDD", 15000, 250},
+	{"BAW12", "EGLL", 10000, "fast"},
+	{"SBI1", "UUWW", 29999, nil},
+	{"AFL123", "LFPG", 0, 0.0},
+}
+
+func TestQuery(t *testing.T) {
+	c, err := New(pilots, accessors, indexes...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		query    string
+		expected []int
+		plan     string
+	}{
+		{`arrival = "UUEE"`, []int{0}, `hash arrival = "UUEE"`},
+		{
+			`arrival = "UUEE" or arrival = "UUWW"`,
+			[]int{0, 3},
+			"union\n  hash arrival = \"UUEE\"\n  hash arrival = \"UUWW\"",
+		},
+		{
+			`callsign =~ "^AFL" and alt >= 10000`,
+			[]int{0, 1},
+			"intersect\n  prefix callsign =~ \"^AFL\"\n  sorted alt >= 10000",
+		},
+		{
+			`callsign =~ "^AFL.*3$" and speed > 100`,
+			[]int{0},
+			"intersect  (verify)\n  prefix callsign =~ \"^AFL.*3$\"  (verify)\n  sorted speed > 100",
+		},
+		{`callsign = "AFL123"`, []int{0, 4}, `prefix callsign = "AFL123"`},
+		{`callsign !~ "^AFL" and alt < 20000`, []int{2}, `sorted alt < 20000  (verify)`},
+		{`callsign =~ "12" or alt = 0`, []int{0, 2, 4}, `scan  (verify)`},
+		{`speed <= 250`, []int{1, 4}, `sorted speed <= 250`},
+		{`arrival > "U"`, []int{0, 1, 3}, `scan  (verify)`},
+	}
+
+	for _, tc := range cases {
+		expr := parse(t, tc.query)
+		rows, err := c.QueryIndices(expr)
+		if err != nil {
+			t.Errorf("unexpected error querying %s: %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(rows, tc.expected) && (len(rows) > 0 || len(tc.expected) > 0) {
+			t.Errorf("%s got %v, expected %v", tc.query, rows, tc.expected)
+		}
+
+		plan, _ := c.Plan(expr)
+		if plan.String() != tc.plan {
+			t.Errorf("%s got plan\n%s\nexpected\n%s", tc.query, plan, tc.plan)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := New(pilots, accessors, Index{"heading", IndexHash}); err == nil || err.Error() != "can't index unknown field heading" {
+		t.Errorf("got %v, expected unknown field error", err)
+	}
+
+	c, _ := New(pilots, accessors)
+	if _, err := c.Query(parse(t, `heading = 1`)); err == nil || !strings.Contains(err.Error(), "unknown identifier heading") {
+		t.Errorf("got %v, expected unknown identifier error", err)
+	}
+}
+
+func randomCondition(r *rand.Rand) string {
+	ops := []string{"=", "!=", "<", "<=", ">", ">="}
+	op := ops[r.Intn(len(ops))]
+	switch r.Intn(5) {
+	case 0:
+		return fmt.Sprintf("alt %s %d", op, r.Intn(5)*1000)
+	case 1:
+		return fmt.Sprintf("speed %s %d", op, r.Intn(5)*100)
+	case 2:
+		return fmt.Sprintf(`arrival %s "%c"`, op, 'A'+r.Intn(5))
+	case 3:
+		patterns := []string{"^A", "^AB", "^AB$", "^", "B", "^A.*B", "^(?i)a"}
+		return fmt.Sprintf(`callsign =~ "%s"`, patterns[r.Intn(len(patterns))])
+	default:
+		return fmt.Sprintf(`callsign %s "%s"`, op, []string{"A", "AB", "B", "ABA"}[r.Intn(4)])
+	}
+}
+
+func randomExpression(r *rand.Rand, depth int) string {
+	n := 1 + r.Intn(3)
+	parts := make([]string, n)
+	for i := range parts {
+		if depth > 0 && r.Intn(3) == 0 {
+			parts[i] = "(" + randomExpression(r, depth-1) + ")"
+		} else {
+			parts[i] = randomCondition(r)
+		}
+	}
+
+	var sb strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteString([]string{" and ", " or "}[r.Intn(2)])
+		}
+		sb.WriteString(part)
+	}
+	return sb.String()
+}
+
+func randomPilots(r *rand.Rand, n int) []pilot {
+	speeds := []any{nil, "fast", 100, 250.5, uint16(300), 400}
+	callsigns := []string{"", "A", "AB", "ABA", "ABB", "B", "BA", "a"}
+	models := make([]pilot, n)
+	for i := range models {
+		models[i] = pilot{
+			Callsign: callsigns[r.Intn(len(callsigns))],
+			Arrival:  string(rune('A' + r.Intn(5))),
+			Alt:      r.Intn(5) * 1000,
+			Speed:    speeds[r.Intn(len(speeds))],
+		}
+	}
+	return models
+}
+
+// TestQueryEquivalent compares queries to filtering with the compiled
+// expression
+func TestQueryEquivalent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	models := randomPilots(r, 300)
+	c, err := New(models, accessors, indexes...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		expr := parse(t, randomExpression(r, 2))
+		program, err := expr.Compile(match.Compiler(accessors))
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", expr, err)
+		}
+
+		expected := program.WithFilterOptions(parser.FilterOptions{Workers: 1}).FilterIndices(models)
+		rows, err := c.QueryIndices(expr)
+		if err != nil {
+			t.Fatalf("error querying %s: %v", expr, err)
+		}
+		if len(rows) != len(expected) || (len(rows) > 0 && !reflect.DeepEqual(rows, expected)) {
+			plan, _ := c.Plan(expr)
+			t.Fatalf("%s got %v, expected %v, plan\n%s", expr, rows, expected, plan)
+		}
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	models := randomPilots(r, 2000)
+	c, _ := New(models, accessors, indexes...)
+	expr := parse(b, `arrival = "A" and alt >= 3000 and callsign =~ "^AB"`)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.QueryIndices(expr)
+		}
+	})
+
+	program, _ := expr.Compile(match.Compiler(accessors))
+	program = program.WithFilterOptions(parser.FilterOptions{Workers: 1})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			program.FilterIndices(models)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package collection
+
+import (
+	"math"
+	"sort"
+
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// hashIndex maps normalized field values to the rows holding them
+	hashIndex struct {
+		rows map[any][]int
+	}
+
+	numEntry struct {
+		value float64
+		row   int
+	}
+
+	strEntry struct {
+		value string
+		row   int
+	}
+
+	// sortedIndex keeps numeric and string field values apart as they
+	// never compare to each other
+	sortedIndex struct {
+		nums []numEntry
+		strs []strEntry
+	}
+
+	// trieNode indexes string field values byte by byte, rows are the
+	// rows whose value ends at the node
+	trieNode struct {
+		children map[byte]*trieNode
+		rows     []int
+	}
+)
+
+// normalize converts a field value to float64 or string, ok is false for
+// values no comparison condition can match
+func normalize(v any) (any, bool) {
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	f, ok := match.ToFloat(v)
+	if !ok || math.IsNaN(f) {
+		return nil, false
+	}
+	return f, true
+}
+
+func newHashIndex(values []any) *hashIndex {
+	idx := &hashIndex{rows: make(map[any][]int)}
+	for row, v := range values {
+		if key, ok := normalize(v); ok {
+			idx.rows[key] = append(idx.rows[key], row)
+		}
+	}
+	return idx
+}
+
+func (idx *hashIndex) lookup(value any) []int {
+	return idx.rows[value]
+}
+
+func newSortedIndex(values []any) *sortedIndex {
+	idx := &sortedIndex{}
+	for row, v := range values {
+		switch key, _ := normalize(v); val := key.(type) {
+		case float64:
+			idx.nums = append(idx.nums, numEntry{val, row})
+		case string:
+			idx.strs = append(idx.strs, strEntry{val, row})
+		}
+	}
+	sort.SliceStable(idx.nums, func(i, j int) bool { return idx.nums[i].value < idx.nums[j].value })
+	sort.SliceStable(idx.strs, func(i, j int) bool { return idx.strs[i].value < idx.strs[j].value })
+	return idx
+}
+
+// span returns the bounds of the entries satisfying op against the value
+// given the position of the first entry >= value and the first > value
+func span(op parser.OperatorType, n, ge, gt int) (int, int) {
+	switch op {
+	case parser.Equals:
+		return ge, gt
+	case parser.Less:
+		return 0, ge
+	case parser.LessOrEqual:
+		return 0, gt
+	case parser.Greater:
+		return gt, n
+	default:
+		return ge, n
+	}
+}
+
+// lookup returns the sorted rows whose value satisfies the comparison
+func (idx *sortedIndex) lookup(op parser.OperatorType, value any) []int {
+	var rows []int
+	switch v := value.(type) {
+	case float64:
+		n := len(idx.nums)
+		ge := sort.Search(n, func(i int) bool { return idx.nums[i].value >= v })
+		gt := sort.Search(n, func(i int) bool { return idx.nums[i].value > v })
+		from, to := span(op, n, ge, gt)
+		for _, e := range idx.nums[from:to] {
+			rows = append(rows, e.row)
+		}
+	case string:
+		n := len(idx.strs)
+		ge := sort.Search(n, func(i int) bool { return idx.strs[i].value >= v })
+		gt := sort.Search(n, func(i int) bool { return idx.strs[i].value > v })
+		from, to := span(op, n, ge, gt)
+		for _, e := range idx.strs[from:to] {
+			rows = append(rows, e.row)
+		}
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+func newTrie(values []any) *trieNode {
+	root := &trieNode{}
+	for row, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		n := root
+		for i := 0; i < len(s); i++ {
+			if n.children == nil {
+				n.children = make(map[byte]*trieNode)
+			}
+			child, found := n.children[s[i]]
+			if !found {
+				child = &trieNode{}
+				n.children[s[i]] = child
+			}
+			n = child
+		}
+		n.rows = append(n.rows, row)
+	}
+	return root
+}
+
+func (n *trieNode) find(prefix string) *trieNode {
+	for i := 0; i < len(prefix) && n != nil; i++ {
+		n = n.children[prefix[i]]
+	}
+	return n
+}
+
+// exact returns the rows whose value equals s
+func (n *trieNode) exact(s string) []int {
+	if n = n.find(s); n == nil {
+		return nil
+	}
+	return n.rows
+}
+
+// prefixed returns the sorted rows whose value starts with the prefix
+func (n *trieNode) prefixed(prefix string) []int {
+	n = n.find(prefix)
+	if n == nil {
+		return nil
+	}
+	var rows []int
+	n.collect(&rows)
+	sort.Ints(rows)
+	return rows
+}
+
+func (n *trieNode) collect(rows *[]int) {
+	*rows = append(*rows, n.rows...)
+	for _, child := range n.children {
+		child.collect(rows)
+	}
+}
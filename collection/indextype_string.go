@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=IndexType -trimprefix=Index"; DO NOT EDIT.
+
+package collection
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[IndexHash-0]
+	_ = x[IndexSorted-1]
+	_ = x[IndexPrefix-2]
+}
+
+const _IndexType_name = "HashSortedPrefix"
+
+var _IndexType_index = [...]uint8{0, 4, 10, 16}
+
+func (i IndexType) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_IndexType_index)-1 {
+		return "IndexType(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _IndexType_name[_IndexType_index[idx]:_IndexType_index[idx+1]]
+}
@@ -0,0 +1,261 @@
+package collection
+
+import (
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/vatsimnerd/lee/logic"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+//go:generate stringer -type=Step -trimprefix=Step
+
+type (
+	Step int
+
+	// Plan is a tree of index lookups combined by intersections and
+	// unions
+	Plan struct {
+		Step Step
+		// Condition is the condition answered by a lookup
+		Condition string
+		Children  []*Plan
+		// Exact is false if the rows found are a superset of the matching
+		// ones, they're verified by evaluating the expression then
+		Exact bool
+
+		run func() []int
+	}
+)
+
+const (
+	// StepScan goes through all the models
+	StepScan Step = iota
+	StepHash
+	StepSorted
+	StepPrefix
+	StepIntersect
+	StepUnion
+)
+
+// String renders the plan as an indented tree, i.e.
+//
+//	intersect
+//	  hash arrival = "UUEE"
+//	  prefix callsign =~ "^AFL.*1"  (verify)
+func (p *Plan) String() string {
+	var sb strings.Builder
+	p.render(&sb, "")
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (p *Plan) render(sb *strings.Builder, indent string) {
+	sb.WriteString(indent + strings.ToLower(p.Step.String()))
+	if p.Condition != "" {
+		sb.WriteString(" " + p.Condition)
+	}
+	if !p.Exact {
+		sb.WriteString("  (verify)")
+	}
+	sb.WriteString("\n")
+	for _, child := range p.Children {
+		child.render(sb, indent+"  ")
+	}
+}
+
+func (c *Collection[T]) plan(expr *parser.Expression[T]) *Plan {
+	if p := c.planNode(logic.FromExpression(expr)); p != nil {
+		return p
+	}
+	return &Plan{Step: StepScan, run: func() []int {
+		rows := make([]int, len(c.models))
+		for i := range rows {
+			rows[i] = i
+		}
+		return rows
+	}}
+}
+
+// planNode returns nil if the node can't be answered by the indexes
+func (c *Collection[T]) planNode(n *logic.Node[T]) *Plan {
+	switch n.Kind {
+	case logic.Cond:
+		return c.planCondition(n.Condition)
+
+	case logic.And:
+		p := &Plan{Step: StepIntersect, Exact: true}
+		for _, child := range n.Children {
+			cp := c.planNode(child)
+			if cp == nil {
+				p.Exact = false
+				continue
+			}
+			p.Exact = p.Exact && cp.Exact
+			p.Children = append(p.Children, cp)
+		}
+		switch len(p.Children) {
+		case 0:
+			return nil
+		case 1:
+			// an and always has several operands, so some of them are
+			// left for verification
+			p.Children[0].Exact = false
+			return p.Children[0]
+		}
+		p.run = func() []int { return intersect(p.Children) }
+		return p
+
+	case logic.Or:
+		p := &Plan{Step: StepUnion, Exact: true}
+		for _, child := range n.Children {
+			cp := c.planNode(child)
+			if cp == nil {
+				// an unindexed operand may match any model
+				return nil
+			}
+			p.Exact = p.Exact && cp.Exact
+			p.Children = append(p.Children, cp)
+		}
+		p.run = func() []int { return c.union(p.Children) }
+		return p
+	}
+	return nil
+}
+
+func (c *Collection[T]) planCondition(cond *parser.Condition[T]) *Plan {
+	field := cond.Identifier.Name
+	op := cond.Operator.Type
+
+	var value any
+	if cond.Value.IsFloat() {
+		value = *cond.Value.Number
+	} else {
+		// the expression compiled, so the string is valid
+		value = cond.Value.MustGetUnquotedStringValue()
+	}
+	str, isString := value.(string)
+
+	p := &Plan{Condition: cond.Format(), Exact: true}
+	switch op {
+	case parser.Equals:
+		if idx, found := c.hash[field]; found {
+			p.Step = StepHash
+			p.run = func() []int { return idx.lookup(value) }
+			return p
+		}
+		if idx, found := c.sorted[field]; found {
+			p.Step = StepSorted
+			p.run = func() []int { return idx.lookup(op, value) }
+			return p
+		}
+		if idx, found := c.prefix[field]; found && isString {
+			p.Step = StepPrefix
+			p.run = func() []int { return idx.exact(str) }
+			return p
+		}
+
+	case parser.Less, parser.LessOrEqual, parser.Greater, parser.GreaterOrEqual:
+		if idx, found := c.sorted[field]; found {
+			p.Step = StepSorted
+			p.run = func() []int { return idx.lookup(op, value) }
+			return p
+		}
+
+	case parser.Matches:
+		idx, found := c.prefix[field]
+		if !found {
+			return nil
+		}
+		prefix, exact, ok := regexpPrefix(str)
+		if !ok {
+			return nil
+		}
+		p.Step = StepPrefix
+		p.Exact = exact
+		p.run = func() []int { return idx.prefixed(prefix) }
+		return p
+	}
+	return nil
+}
+
+// regexpPrefix returns the literal prefix every string matching the
+// pattern starts with, exact is set if the pattern matches every string
+// with the prefix. The pattern must be anchored at the start of the text.
+func regexpPrefix(pattern string) (prefix string, exact bool, ok bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false, false
+	}
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	if subs[0].Op != syntax.OpBeginText {
+		return "", false, false
+	}
+	if len(subs) == 1 {
+		return "", true, true
+	}
+
+	lit := subs[1]
+	if lit.Op != syntax.OpLiteral || lit.Flags&syntax.FoldCase != 0 {
+		return "", false, true
+	}
+	return string(lit.Rune), len(subs) == 2, true
+}
+
+// intersect merges the sorted rows of the plans, smallest first so the
+// intermediate results shrink quickly
+func intersect(plans []*Plan) []int {
+	sets := make([][]int, len(plans))
+	for i, p := range plans {
+		sets[i] = p.run()
+	}
+	sort.Slice(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+
+	result := sets[0]
+	for _, set := range sets[1:] {
+		if len(result) == 0 {
+			break
+		}
+		var merged []int
+		i, j := 0, 0
+		for i < len(result) && j < len(set) {
+			switch {
+			case result[i] < set[j]:
+				i++
+			case result[i] > set[j]:
+				j++
+			default:
+				merged = append(merged, result[i])
+				i++
+				j++
+			}
+		}
+		result = merged
+	}
+	return result
+}
+
+func (c *Collection[T]) union(plans []*Plan) []int {
+	seen := make([]bool, len(c.models))
+	count := 0
+	for _, p := range plans {
+		for _, row := range p.run() {
+			if !seen[row] {
+				seen[row] = true
+				count++
+			}
+		}
+	}
+
+	rows := make([]int, 0, count)
+	for row, ok := range seen {
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
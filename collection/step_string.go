@@ -0,0 +1,29 @@
+// Code generated by "stringer -type=Step -trimprefix=Step"; DO NOT EDIT.
+
+package collection
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[StepScan-0]
+	_ = x[StepHash-1]
+	_ = x[StepSorted-2]
+	_ = x[StepPrefix-3]
+	_ = x[StepIntersect-4]
+	_ = x[StepUnion-5]
+}
+
+const _Step_name = "ScanHashSortedPrefixIntersectUnion"
+
+var _Step_index = [...]uint8{0, 4, 8, 14, 20, 29, 34}
+
+func (i Step) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Step_index)-1 {
+		return "Step(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Step_name[_Step_index[idx]:_Step_index[idx+1]]
+}
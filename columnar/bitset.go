@@ -0,0 +1,83 @@
+package columnar
+
+import "math/bits"
+
+// Bitset holds one bit per row of a table
+type Bitset struct {
+	words []uint64
+	len   int
+}
+
+func newBitset(n int) *Bitset {
+	return &Bitset{words: make([]uint64, (n+63)/64), len: n}
+}
+
+// Len returns the number of rows
+func (b *Bitset) Len() int {
+	return b.len
+}
+
+func (b *Bitset) set(i int) {
+	b.words[i/64] |= 1 << (i % 64)
+}
+
+// Test reports whether the bit of the row is set
+func (b *Bitset) Test(i int) bool {
+	return b.words[i/64]&(1<<(i%64)) != 0
+}
+
+// Count returns the number of set bits
+func (b *Bitset) Count() int {
+	count := 0
+	for _, w := range b.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// Indices returns the rows whose bits are set in ascending order
+func (b *Bitset) Indices() []int {
+	indices := make([]int, 0, b.Count())
+	for i, w := range b.words {
+		for w != 0 {
+			indices = append(indices, i*64+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return indices
+}
+
+func (b *Bitset) none() bool {
+	for _, w := range b.words {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bitset) and(other *Bitset) {
+	for i := range b.words {
+		b.words[i] &= other.words[i]
+	}
+}
+
+func (b *Bitset) or(other *Bitset) {
+	for i := range b.words {
+		b.words[i] |= other.words[i]
+	}
+}
+
+// not flips every bit, leaving the padding of the last word clear
+func (b *Bitset) not() {
+	for i := range b.words {
+		b.words[i] = ^b.words[i]
+	}
+	if tail := b.len % 64; tail != 0 {
+		b.words[len(b.words)-1] &= 1<<tail - 1
+	}
+}
+
+func (b *Bitset) clone() *Bitset {
+	return &Bitset{words: append([]uint64(nil), b.words...), len: b.len}
+}
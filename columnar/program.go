@@ -0,0 +1,308 @@
+// Package columnar evaluates expressions over column-oriented snapshots.
+// Every condition scans its column at once producing a bitset of the
+// matching rows, and/or chains are bitwise operations on the bitsets. The
+// results are the same as of programs compiled with match.Compiler.
+package columnar
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/logic"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// kernel evaluates a condition over a column. Negations are evaluated
+	// as the positive operator and flipped afterwards, != and !~ are
+	// strict negations of = and =~.
+	kernel struct {
+		column string
+		op     parser.OperatorType
+		negate bool
+
+		isNum bool
+		num   float64
+		str   string
+		re    *regexp.Regexp
+		// pred is the row by row fallback for columns of mixed types
+		pred match.Predicate
+
+		// token is the identifier token positioning the errors
+		token *lexer.Token
+	}
+
+	node struct {
+		kernel   *kernel
+		op       parser.CombineOperatorType
+		children []*node
+	}
+
+	// Program is an expression compiled for tables, it's safe for
+	// concurrent use
+	Program struct {
+		root *node
+	}
+)
+
+// Compile prepares the expression for evaluation over tables, identifiers
+// are column names
+func Compile[T any](expr *parser.Expression[T]) (*Program, error) {
+	root, err := compileNode(logic.FromExpression(expr))
+	if err != nil {
+		return nil, err
+	}
+	return &Program{root: root}, nil
+}
+
+func compileNode[T any](n *logic.Node[T]) (*node, error) {
+	if n.Kind == logic.Cond {
+		k, err := compileKernel(n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return &node{kernel: k}, nil
+	}
+
+	cn := &node{op: parser.And}
+	if n.Kind == logic.Or {
+		cn.op = parser.Or
+	}
+	for _, child := range n.Children {
+		c, err := compileNode(child)
+		if err != nil {
+			return nil, err
+		}
+		cn.children = append(cn.children, c)
+	}
+	return cn, nil
+}
+
+func compileKernel[T any](c *parser.Condition[T]) (*kernel, error) {
+	pred, err := match.NewPredicate(c)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &kernel{column: c.Identifier.Name, op: c.Operator.Type, pred: pred, token: c.Identifier.Token}
+
+	switch k.op {
+	case parser.NotEquals:
+		k.op, k.negate = parser.Equals, true
+	case parser.NotMatches:
+		k.op, k.negate = parser.Matches, true
+	}
+
+	if c.Value.IsFloat() {
+		k.isNum, k.num = true, *c.Value.Number
+		return k, nil
+	}
+
+	// NewPredicate validated the string and the regular expression
+	k.str = c.Value.MustGetUnquotedStringValue()
+	if k.op == parser.Matches {
+		k.re = regexp.MustCompile(k.str)
+	}
+	return k, nil
+}
+
+// Evaluate returns the bitset of the table rows matching the expression,
+// identifiers missing from the table are errors
+func (p *Program) Evaluate(t *Table) (*Bitset, error) {
+	return p.root.eval(t, nil)
+}
+
+// Filter returns the models at the rows matching the expression, the
+// table must have been built from the models
+func Filter[T any](p *Program, t *Table, models []T) ([]T, error) {
+	if len(models) != t.rows {
+		return nil, fmt.Errorf("got %d models for a table of %d rows", len(models), t.rows)
+	}
+	b, err := p.Evaluate(t)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]T, 0, b.Count())
+	for _, i := range b.Indices() {
+		result = append(result, models[i])
+	}
+	return result, nil
+}
+
+// eval returns the bitset of the rows matching the node. Only the rows
+// set in relevant need the right result, the others are decided by the
+// enclosing chain already, which lets expensive conditions skip them. A nil
+// relevant means all the rows.
+func (n *node) eval(t *Table, relevant *Bitset) (*Bitset, error) {
+	if n.kernel != nil {
+		return n.kernel.eval(t, relevant)
+	}
+
+	acc, err := n.children[0].eval(t, relevant)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range n.children[1:] {
+		// the rows the next operand can still change: the true ones for an
+		// and, the false ones for an or
+		rest := acc.clone()
+		if n.op == parser.Or {
+			rest.not()
+		}
+		if relevant != nil {
+			rest.and(relevant)
+		}
+
+		if rest.none() {
+			// unknown columns are reported even if they aren't evaluated
+			if err := child.check(t); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		b, err := child.eval(t, rest)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == parser.And {
+			acc.and(b)
+		} else {
+			acc.or(b)
+		}
+	}
+	return acc, nil
+}
+
+func (n *node) check(t *Table) error {
+	if n.kernel != nil {
+		_, err := n.kernel.lookup(t)
+		return err
+	}
+	for _, child := range n.children {
+		if err := child.check(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *kernel) lookup(t *Table) (*column, error) {
+	col, found := t.columns[k.column]
+	if !found {
+		return nil, lexer.ErrorAt(k.token, "unknown identifier %s", k.column)
+	}
+	return col, nil
+}
+
+func (k *kernel) eval(t *Table, relevant *Bitset) (*Bitset, error) {
+	col, err := k.lookup(t)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBitset(t.rows)
+	switch {
+	case col.values != nil:
+		// the predicate handles negation itself
+		for i, v := range col.values {
+			if relevant != nil && !relevant.Test(i) {
+				continue
+			}
+			if k.pred(v) {
+				b.set(i)
+			}
+		}
+		return b, nil
+	case col.floats != nil && k.isNum:
+		k.floats(col.floats, b)
+	case col.strings != nil && !k.isNum:
+		k.strings(col.strings, b, relevant)
+	}
+	// otherwise the types differ and nothing matches
+
+	if k.negate {
+		b.not()
+	}
+	return b, nil
+}
+
+func (k *kernel) floats(col []float64, b *Bitset) {
+	v := k.num
+	switch k.op {
+	case parser.Equals:
+		for i, f := range col {
+			if f == v {
+				b.set(i)
+			}
+		}
+	case parser.Less:
+		for i, f := range col {
+			if f < v {
+				b.set(i)
+			}
+		}
+	case parser.LessOrEqual:
+		for i, f := range col {
+			if f <= v {
+				b.set(i)
+			}
+		}
+	case parser.Greater:
+		for i, f := range col {
+			if f > v {
+				b.set(i)
+			}
+		}
+	case parser.GreaterOrEqual:
+		for i, f := range col {
+			if f >= v {
+				b.set(i)
+			}
+		}
+	}
+}
+
+func (k *kernel) strings(col []string, b *Bitset, relevant *Bitset) {
+	v := k.str
+	switch k.op {
+	case parser.Equals:
+		for i, s := range col {
+			if s == v {
+				b.set(i)
+			}
+		}
+	case parser.Less:
+		for i, s := range col {
+			if s < v {
+				b.set(i)
+			}
+		}
+	case parser.LessOrEqual:
+		for i, s := range col {
+			if s <= v {
+				b.set(i)
+			}
+		}
+	case parser.Greater:
+		for i, s := range col {
+			if s > v {
+				b.set(i)
+			}
+		}
+	case parser.GreaterOrEqual:
+		for i, s := range col {
+			if s >= v {
+				b.set(i)
+			}
+		}
+	case parser.Matches:
+		for i, s := range col {
+			if (relevant == nil || relevant.Test(i)) && k.re.MatchString(s) {
+				b.set(i)
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package columnar
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type pilot struct {
+	Callsign string
+	Arrival  string
+	Alt      int
+	Speed    any
+}
+
+var accessors = map[string]match.Accessor[pilot]{
+	"callsign": func(p pilot) any { return p.Callsign },
+	"arrival":  func(p pilot) any { return p.Arrival },
+	"alt":      func(p pilot) any { return p.Alt },
+	"speed":    func(p pilot) any { return p.Speed },
+}
+
+func parse(t testing.TB, src string) *parser.Expression[pilot] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[pilot](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+func TestEvaluate(t *testing.T) {
+	pilots := []pilot{
+		{"AFL123", "UUEE", 35000, 450},
+		{"AFL9", "UUDD", 15000, 250},
+		{"BAW12", "EGLL", 10000, "fast"},
+		{"SBI1", "UUWW", 29999, nil},
+	}
+	table := FromModels(pilots, accessors)
+
+	cases := []struct {
+		query    string
+		expected []int
+	}{
+		{`callsign =~ "^AFL" and alt > 20000`, []int{0}},
+		{`arrival = "UUEE" or arrival = "UUWW"`, []int{0, 3}},
+		{`speed > 200`, []int{0, 1}},
+		{`speed != 250`, []int{0, 2, 3}},
+		{`alt != "x"`, []int{0, 1, 2, 3}},
+		{`alt = "x" or callsign !~ "^AFL"`, []int{2, 3}},
+		{`callsign < 5`, []int{}},
+		{`alt >= 15000 and (speed = "fast" or callsign =~ "9$")`, []int{1}},
+	}
+
+	for _, tc := range cases {
+		p, err := Compile(parse(t, tc.query))
+		if err != nil {
+			t.Errorf("unexpected error compiling %s: %v", tc.query, err)
+			continue
+		}
+		b, err := p.Evaluate(table)
+		if err != nil {
+			t.Errorf("unexpected error evaluating %s: %v", tc.query, err)
+			continue
+		}
+		if rows := b.Indices(); !reflect.DeepEqual(rows, tc.expected) {
+			t.Errorf("%s got %v, expected %v", tc.query, rows, tc.expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	table := NewTable(2)
+	if err := table.SetFloats("alt", []float64{1}); err == nil || err.Error() != "column alt has 1 rows, expected 2" {
+		t.Errorf("got %v, expected a length error", err)
+	}
+	table.SetFloats("alt", []float64{1, 2})
+
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{`heading > 1`, "unknown identifier heading at line 1 pos 1"},
+		{`alt > 5 and heading > 1`, "unknown identifier heading at line 1 pos 13"},
+		{`alt =~ "("`, "invalid regular expression"},
+	}
+	for _, tc := range cases {
+		p, err := Compile(parse(t, tc.query))
+		if err == nil {
+			_, err = p.Evaluate(table)
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s got %v, expected %s", tc.query, err, tc.err)
+		}
+	}
+
+	// synthesized tokens, like the decoded ones, have no position
+	expr := parse(t, `heading > 1`)
+	expr.Left.Condition.Identifier.Token = &lexer.Token{Type: lexer.Identifier, Literal: "heading"}
+	p, err := Compile(expr)
+	if err == nil {
+		_, err = p.Evaluate(table)
+	}
+	if err == nil || err.Error() != "unknown identifier heading" {
+		t.Errorf("got %v, expected the unknown identifier error without a position", err)
+	}
+}
+
+func randomCondition(r *rand.Rand) string {
+	ops := []string{"=", "!=", "<", "<=", ">", ">="}
+	op := ops[r.Intn(len(ops))]
+	switch r.Intn(5) {
+	case 0:
+		return fmt.Sprintf("alt %s %d", op, r.Intn(5)*1000)
+	case 1:
+		if r.Intn(3) == 0 {
+			return fmt.Sprintf(`speed %s "fast"`, op)
+		}
+		return fmt.Sprintf("speed %s %d", op, r.Intn(5)*100)
+	case 2:
+		return fmt.Sprintf(`arrival %s "%c"`, op, 'A'+r.Intn(5))
+	case 3:
+		return fmt.Sprintf(`callsign %s "^%c"`, []string{"=~", "!~"}[r.Intn(2)], 'A'+r.Intn(5))
+	default:
+		return fmt.Sprintf(`callsign %s %d`, op, r.Intn(3))
+	}
+}
+
+func randomExpression(r *rand.Rand, depth int) string {
+	n := 1 + r.Intn(3)
+	parts := make([]string, n)
+	for i := range parts {
+		if depth > 0 && r.Intn(3) == 0 {
+			parts[i] = "(" + randomExpression(r, depth-1) + ")"
+		} else {
+			parts[i] = randomCondition(r)
+		}
+	}
+
+	var sb strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteString([]string{" and ", " or "}[r.Intn(2)])
+		}
+		sb.WriteString(part)
+	}
+	return sb.String()
+}
+
+func randomPilots(r *rand.Rand, n int) []pilot {
+	speeds := []any{nil, "fast", 100, 250.5, uint16(300), 400}
+	models := make([]pilot, n)
+	for i := range models {
+		models[i] = pilot{
+			Callsign: string(rune('A'+r.Intn(5))) + "X",
+			Arrival:  string(rune('A' + r.Intn(5))),
+			Alt:      r.Intn(5) * 1000,
+			Speed:    speeds[r.Intn(len(speeds))],
+		}
+	}
+	return models
+}
+
+// TestEvaluateEquivalent compares the columnar evaluation to programs
+// compiled with match.Compiler
+func TestEvaluateEquivalent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// not a multiple of 64 to catch bits set past the last row
+	models := randomPilots(r, 300)
+	table := FromModels(models, accessors)
+
+	for i := 0; i < 1000; i++ {
+		expr := parse(t, randomExpression(r, 2))
+		program, err := expr.Compile(match.Compiler(accessors))
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", expr, err)
+		}
+		expected := program.WithFilterOptions(parser.FilterOptions{Workers: 1}).FilterIndices(models)
+
+		p, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", expr, err)
+		}
+		b, err := p.Evaluate(table)
+		if err != nil {
+			t.Fatalf("error evaluating %s: %v", expr, err)
+		}
+		if rows := b.Indices(); len(rows) != len(expected) || (len(rows) > 0 && !reflect.DeepEqual(rows, expected)) {
+			t.Fatalf("%s got %v, expected %v", expr, rows, expected)
+		}
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	models := randomPilots(r, 2000)
+	expr := parse(b, `alt >= 3000 and (arrival = "A" or arrival = "B") and callsign =~ "^[AB]"`)
+
+	b.Run("columnar", func(b *testing.B) {
+		table := FromModels(models, accessors)
+		p, _ := Compile(expr)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.Evaluate(table)
+		}
+	})
+
+	b.Run("rows", func(b *testing.B) {
+		program, _ := expr.Compile(match.Compiler(accessors))
+		program = program.WithFilterOptions(parser.FilterOptions{Workers: 1})
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			program.FilterIndices(models)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package columnar
+
+import (
+	"fmt"
+
+	"github.com/vatsimnerd/lee/match"
+)
+
+type (
+	// column holds the values of a field, only one of the slices is set.
+	// Typed columns are evaluated by tight loops, values of mixed types
+	// fall back to the match package predicates.
+	column struct {
+		floats  []float64
+		strings []string
+		values  []any
+	}
+
+	// Table is a column-oriented snapshot of models, every column has
+	// a value per row
+	Table struct {
+		rows    int
+		columns map[string]*column
+	}
+)
+
+// NewTable makes a table without columns
+func NewTable(rows int) *Table {
+	return &Table{rows: rows, columns: make(map[string]*column)}
+}
+
+// FromModels builds a table with a column per accessor. A column gets
+// a typed representation if all of its values are numbers or all of them
+// are strings.
+func FromModels[T any](models []T, accessors map[string]match.Accessor[T]) *Table {
+	t := NewTable(len(models))
+	for name, accessor := range accessors {
+		values := make([]any, len(models))
+		numeric, strs := true, true
+		for i, model := range models {
+			values[i] = accessor(model)
+			if _, ok := match.ToFloat(values[i]); !ok {
+				numeric = false
+			}
+			if _, ok := values[i].(string); !ok {
+				strs = false
+			}
+		}
+
+		switch {
+		case numeric:
+			floats := make([]float64, len(values))
+			for i, v := range values {
+				floats[i], _ = match.ToFloat(v)
+			}
+			t.columns[name] = &column{floats: floats}
+		case strs:
+			strings := make([]string, len(values))
+			for i, v := range values {
+				strings[i] = v.(string)
+			}
+			t.columns[name] = &column{strings: strings}
+		default:
+			t.columns[name] = &column{values: values}
+		}
+	}
+	return t
+}
+
+// Rows returns the number of rows
+func (t *Table) Rows() int {
+	return t.rows
+}
+
+func (t *Table) checkLen(name string, n int) error {
+	if n != t.rows {
+		return fmt.Errorf("column %s has %d rows, expected %d", name, n, t.rows)
+	}
+	return nil
+}
+
+// SetFloats sets a numeric column, the table keeps the slice
+func (t *Table) SetFloats(name string, values []float64) error {
+	if err := t.checkLen(name, len(values)); err != nil {
+		return err
+	}
+	t.columns[name] = &column{floats: values}
+	return nil
+}
+
+// SetStrings sets a string column, the table keeps the slice
+func (t *Table) SetStrings(name string, values []string) error {
+	if err := t.checkLen(name, len(values)); err != nil {
+		return err
+	}
+	t.columns[name] = &column{strings: values}
+	return nil
+}
+
+// SetValues sets a column of values of any type, the table keeps the
+// slice
+func (t *Table) SetValues(name string, values []any) error {
+	if err := t.checkLen(name, len(values)); err != nil {
+		return err
+	}
+	t.columns[name] = &column{values: values}
+	return nil
+}
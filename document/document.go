@@ -0,0 +1,140 @@
+// Package document compiles filters over loosely typed documents, i.e.
+// JSON decoded into map[string]any. Identifiers are dotted paths into
+// nested maps, numeric parts of a path index slices: flight_plan.arrival,
+// legs.0.fix.
+//
+// Values are compared with the match semantics: numbers of any Go numeric
+// type or json.Number only match number conditions and strings only match
+// string conditions. Bools match the strings "true" and "false", the way
+// JSON spells them. Other values, including null, objects and arrays,
+// only satisfy != and !~.
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+//go:generate stringer -type=Missing
+
+type (
+	// Missing decides what conditions on paths which don't resolve do
+	Missing int
+
+	Options struct {
+		Missing Missing
+	}
+)
+
+const (
+	// MissingNull treats missing keys as null, so only != and !~ match them
+	MissingNull Missing = iota
+	// MissingFalse makes every condition on a missing key false, negated
+	// ones included
+	MissingFalse
+	// MissingError makes the conditions fail with ErrMissing, EvaluateE
+	// reports the error and Evaluate treats the condition as false
+	MissingError
+)
+
+// ErrMissing is wrapped by the errors of conditions on missing keys
+var ErrMissing = errors.New("missing key")
+
+// Lookup resolves a dotted path in the document, found is false if any
+// part of the path doesn't exist. A present null is found.
+func Lookup(doc any, path string) (value any, found bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]any:
+			if doc, found = node[key]; !found {
+				return nil, false
+			}
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			doc = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// value resolves the path converting bools to the strings they're compared
+// with
+func value(doc map[string]any, path string) (any, bool) {
+	v, found := Lookup(doc, path)
+	if b, ok := v.(bool); ok {
+		return strconv.FormatBool(b), found
+	}
+	return v, found
+}
+
+// CompilerE returns a compilation callback for documents applying the
+// missing keys policy of the options
+func CompilerE(opts Options) parser.CompilationCallbackE[map[string]any] {
+	return func(c *parser.Condition[map[string]any]) (parser.MatcherE[map[string]any], error) {
+		pred, err := match.NewPredicate(c)
+		if err != nil {
+			return nil, err
+		}
+
+		path := c.Identifier.Name
+		switch opts.Missing {
+		case MissingNull:
+			return func(ctx context.Context, doc map[string]any) (bool, error) {
+				v, _ := value(doc, path)
+				return pred(v), nil
+			}, nil
+		case MissingFalse:
+			return func(ctx context.Context, doc map[string]any) (bool, error) {
+				v, found := value(doc, path)
+				return found && pred(v), nil
+			}, nil
+		case MissingError:
+			return func(ctx context.Context, doc map[string]any) (bool, error) {
+				v, found := value(doc, path)
+				if !found {
+					return false, fmt.Errorf("%w %s", ErrMissing, path)
+				}
+				return pred(v), nil
+			}, nil
+		default:
+			return nil, fmt.Errorf("unknown missing keys policy %s", opts.Missing)
+		}
+	}
+}
+
+// Compiler returns a compilation callback for documents which can't fail,
+// MissingError is treated as MissingFalse
+func Compiler(opts Options) parser.CompilationCallback[map[string]any] {
+	if opts.Missing == MissingError {
+		opts.Missing = MissingFalse
+	}
+	cb := CompilerE(opts)
+	return func(c *parser.Condition[map[string]any]) (parser.Matcher[map[string]any], error) {
+		m, err := cb(c)
+		if err != nil {
+			return nil, err
+		}
+		return func(doc map[string]any) bool {
+			result, _ := m(context.Background(), doc)
+			return result
+		}, nil
+	}
+}
+
+// Fields reports document values in explanations
+func Fields() parser.FieldFunc[map[string]any] {
+	return func(doc map[string]any, identifier string) (any, bool) {
+		return Lookup(doc, identifier)
+	}
+}
@@ -0,0 +1,132 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+const testDocument = `{
+	"callsign": "BAW117",
+	"altitude": 35000,
+	"heavy": true,
+	"remarks": null,
+	"flight_plan": {"departure": "EGLL", "arrival": "KJFK", "cruise_tas": 488.5},
+	"legs": [{"fix": "CPT", "altitude": 6000}, {"fix": "KENET"}]
+}`
+
+func decode(t *testing.T, useNumber bool) map[string]any {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader([]byte(testDocument)))
+	if useNumber {
+		dec.UseNumber()
+	}
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func parse(t *testing.T, src string) *parser.Expression[map[string]any] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[map[string]any](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+type documentCase struct {
+	src string
+	// results with MissingNull, MissingFalse and MissingError
+	results [3]bool
+}
+
+var documentCases = []documentCase{
+	{`callsign = "BAW117" and altitude > 30000`, [3]bool{true, true, true}},
+	{`flight_plan.arrival =~ "^K" and flight_plan.cruise_tas < 500`, [3]bool{true, true, true}},
+	{`legs.0.fix = "CPT" and legs.0.altitude = 6000`, [3]bool{true, true, true}},
+	{`legs.1.altitude != 6000`, [3]bool{true, false, false}},
+	{`legs.2.fix != "CPT"`, [3]bool{true, false, false}},
+	{`heavy = "true"`, [3]bool{true, true, true}},
+	{`heavy != "false" and heavy != 1`, [3]bool{true, true, true}},
+	{`remarks != "x" and remarks !~ "x"`, [3]bool{true, true, true}},
+	{`remarks = "x" or flight_plan = "x" or legs = 1`, [3]bool{false, false, false}},
+	{`altitude = "35000" or callsign = 1`, [3]bool{false, false, false}},
+	{`missing.key != 1`, [3]bool{true, false, false}},
+}
+
+func TestCompiler(t *testing.T) {
+	policies := []Missing{MissingNull, MissingFalse, MissingError}
+
+	for _, useNumber := range []bool{false, true} {
+		doc := decode(t, useNumber)
+		for _, tc := range documentCases {
+			for i, policy := range policies {
+				p, err := parse(t, tc.src).Compile(Compiler(Options{Missing: policy}))
+				if err != nil {
+					t.Fatalf("error compiling %s: %v", tc.src, err)
+				}
+				if result := p.Evaluate(doc); result != tc.results[i] {
+					t.Errorf("%s with %s and json.Number %v got %v, expected %v", tc.src, policy, useNumber, result, tc.results[i])
+				}
+			}
+		}
+	}
+}
+
+func TestCompilerE(t *testing.T) {
+	doc := decode(t, true)
+	p, err := parse(t, `callsign = "BAW117" and flight_plan.route =~ "CPT"`).CompileE(CompilerE(Options{Missing: MissingError}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.EvaluateE(context.Background(), doc)
+	if err == nil || err.Error() != `error evaluating flight_plan.route =~ "CPT" at line 1 pos 25: missing key flight_plan.route` {
+		t.Errorf("got %v, expected the missing key error", err)
+	}
+	if !errors.Is(err, ErrMissing) {
+		t.Errorf("error must wrap ErrMissing")
+	}
+	if p.Evaluate(doc) {
+		t.Errorf("Evaluate must treat the failed condition as false")
+	}
+
+	if _, err := parse(t, `a = 1`).CompileE(CompilerE(Options{Missing: 7})); err == nil {
+		t.Errorf("unknown policy must fail")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	doc := decode(t, false)
+	cases := []struct {
+		path  string
+		value any
+		found bool
+	}{
+		{"callsign", "BAW117", true},
+		{"remarks", nil, true},
+		{"legs.1.fix", "KENET", true},
+		{"legs.-1.fix", nil, false},
+		{"legs.x", nil, false},
+		{"callsign.x", nil, false},
+		{"flight_plan.cruise_tas", 488.5, true},
+	}
+	for _, tc := range cases {
+		value, found := Lookup(doc, tc.path)
+		if value != tc.value || found != tc.found {
+			t.Errorf("%s got %v %v, expected %v %v", tc.path, value, found, tc.value, tc.found)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=Missing"; DO NOT EDIT.
+
+package document
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MissingNull-0]
+	_ = x[MissingFalse-1]
+	_ = x[MissingError-2]
+}
+
+const _Missing_name = "MissingNullMissingFalseMissingError"
+
+var _Missing_index = [...]uint8{0, 11, 23, 35}
+
+func (i Missing) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Missing_index)-1 {
+		return "Missing(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Missing_name[_Missing_index[idx]:_Missing_index[idx+1]]
+}
@@ -0,0 +1,277 @@
+// Package engine matches models against many registered expressions at
+// once. Conditions comparing a field to a constant with =, <, <=, > or >=
+// are indexed: every distinct condition is evaluated once per model by
+// looking the field value up in a hash map or a sorted list of bounds, and
+// the subscriptions are found by counting their satisfied conditions
+// instead of evaluating every expression.
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vatsimnerd/lee/logic"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Engine keeps a set of subscriptions, it's safe for concurrent use
+	Engine[T any] struct {
+		mu        sync.RWMutex
+		accessors map[string]match.Accessor[T]
+		compile   parser.CompilationCallback[T]
+
+		subs       map[string]*subscription[T]
+		handles    map[int]*subscription[T]
+		nextHandle int
+
+		preds    map[predKey]*predicate
+		predByID map[int]*predicate
+		nextPred int
+		fields   map[string]*fieldIndex
+
+		// subscriptions without a guard, evaluated for every model
+		scan map[int]*subscription[T]
+	}
+
+	subscription[T any] struct {
+		id      string
+		handle  int
+		program *parser.Program[T]
+		// preds are the predicates notifying the subscription
+		preds []*predicate
+		// exact subscriptions are conjunctions of indexed predicates which
+		// match once all of them hold. Others are candidates whenever any
+		// of their guard predicates holds and need to be verified.
+		exact bool
+	}
+)
+
+// New makes an empty engine reading identifiers with the given accessors
+func New[T any](accessors map[string]match.Accessor[T]) *Engine[T] {
+	return &Engine[T]{
+		accessors: accessors,
+		compile:   match.Compiler(accessors),
+		subs:      make(map[string]*subscription[T]),
+		handles:   make(map[int]*subscription[T]),
+		preds:     make(map[predKey]*predicate),
+		predByID:  make(map[int]*predicate),
+		fields:    make(map[string]*fieldIndex),
+		scan:      make(map[int]*subscription[T]),
+	}
+}
+
+// Len returns the number of subscriptions
+func (e *Engine[T]) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.subs)
+}
+
+// Add registers the expression under the id. The expression is compiled
+// with match.Compiler, so unknown identifiers and invalid regular
+// expressions are reported here.
+func (e *Engine[T]) Add(id string, expr *parser.Expression[T]) error {
+	program, err := expr.Compile(e.compile)
+	if err != nil {
+		return err
+	}
+	if params := program.Params(); params != nil {
+		return fmt.Errorf("expression has unbound parameters %s", strings.Join(params, ", "))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, found := e.subs[id]; found {
+		return fmt.Errorf("subscription %s already exists", id)
+	}
+
+	sub := &subscription[T]{id: id, handle: e.nextHandle, program: program}
+	e.nextHandle++
+
+	node := logic.FromExpression(expr)
+	var conds []*parser.Condition[T]
+	if conj, ok := conjunction(node); ok {
+		conds = conj
+		sub.exact = true
+	} else {
+		conds = guard(node)
+	}
+
+	seen := make(map[*predicate]bool)
+	for _, c := range conds {
+		p := e.acquire(c)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		p.subs[sub.handle] = struct{}{}
+		sub.preds = append(sub.preds, p)
+	}
+
+	e.subs[id] = sub
+	e.handles[sub.handle] = sub
+	if len(sub.preds) == 0 {
+		e.scan[sub.handle] = sub
+	}
+	return nil
+}
+
+// Remove unregisters the subscription, it returns false if there's no
+// subscription with the id
+func (e *Engine[T]) Remove(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sub, found := e.subs[id]
+	if !found {
+		return false
+	}
+
+	for _, p := range sub.preds {
+		delete(p.subs, sub.handle)
+		if len(p.subs) == 0 {
+			e.release(p)
+		}
+	}
+	delete(e.subs, id)
+	delete(e.handles, sub.handle)
+	delete(e.scan, sub.handle)
+	return true
+}
+
+// Match returns the sorted ids of the subscriptions matching the model
+func (e *Engine[T]) Match(model T) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for field, fi := range e.fields {
+		fi.lookup(e.accessors[field](model), func(id int) {
+			for handle := range e.predByID[id].subs {
+				counts[handle]++
+			}
+		})
+	}
+
+	var ids []string
+	for handle, count := range counts {
+		sub := e.handles[handle]
+		if sub.exact {
+			if count == len(sub.preds) {
+				ids = append(ids, sub.id)
+			}
+		} else if sub.program.Evaluate(model) {
+			ids = append(ids, sub.id)
+		}
+	}
+	for _, sub := range e.scan {
+		if sub.program.Evaluate(model) {
+			ids = append(ids, sub.id)
+		}
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+func keyFor[T any](c *parser.Condition[T]) predKey {
+	key := predKey{field: c.Identifier.Name, op: c.Operator.Type}
+	if c.Value.IsFloat() {
+		key.value = *c.Value.Number
+	} else {
+		// the expression compiled, so the string is valid
+		key.value = c.Value.MustGetUnquotedStringValue()
+	}
+	return key
+}
+
+// acquire returns the predicate for the condition, adding it to the
+// index if it's new
+func (e *Engine[T]) acquire(c *parser.Condition[T]) *predicate {
+	key := keyFor(c)
+	if p, found := e.preds[key]; found {
+		return p
+	}
+
+	p := &predicate{id: e.nextPred, key: key, subs: make(map[int]struct{})}
+	e.nextPred++
+	e.preds[key] = p
+	e.predByID[p.id] = p
+
+	fi, found := e.fields[key.field]
+	if !found {
+		fi = newFieldIndex()
+		e.fields[key.field] = fi
+	}
+	fi.add(p)
+	return p
+}
+
+func (e *Engine[T]) release(p *predicate) {
+	fi := e.fields[p.key.field]
+	fi.remove(p)
+	if fi.empty() {
+		delete(e.fields, p.key.field)
+	}
+	delete(e.preds, p.key)
+	delete(e.predByID, p.id)
+}
+
+// conjunction returns the conditions of a node which is an indexable
+// condition or an and of them
+func conjunction[T any](n *logic.Node[T]) ([]*parser.Condition[T], bool) {
+	switch n.Kind {
+	case logic.Cond:
+		if indexable[n.Condition.Operator.Type] {
+			return []*parser.Condition[T]{n.Condition}, true
+		}
+	case logic.And:
+		conds := make([]*parser.Condition[T], 0, len(n.Children))
+		for _, child := range n.Children {
+			if child.Kind != logic.Cond || !indexable[child.Condition.Operator.Type] {
+				return nil, false
+			}
+			conds = append(conds, child.Condition)
+		}
+		return conds, true
+	}
+	return nil, false
+}
+
+// guard returns indexable conditions at least one of which holds whenever
+// the node does, nil if there's no such set. An and is guarded by its
+// smallest guarded operand, an or by the union of its operands' guards,
+// which turns ors of equalities into an IN lookup.
+func guard[T any](n *logic.Node[T]) []*parser.Condition[T] {
+	switch n.Kind {
+	case logic.Cond:
+		if indexable[n.Condition.Operator.Type] {
+			return []*parser.Condition[T]{n.Condition}
+		}
+	case logic.And:
+		var best []*parser.Condition[T]
+		for _, child := range n.Children {
+			g := guard(child)
+			if g != nil && (best == nil || len(g) < len(best)) {
+				best = g
+			}
+		}
+		return best
+	case logic.Or:
+		var union []*parser.Condition[T]
+		for _, child := range n.Children {
+			g := guard(child)
+			if g == nil {
+				return nil
+			}
+			union = append(union, g...)
+		}
+		return union
+	}
+	return nil
+}
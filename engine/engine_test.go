@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type flight struct {
+	Callsign string
+	Arrival  string
+	Alt      int
+	Speed    any
+}
+
+var accessors = map[string]match.Accessor[flight]{
+	"callsign": func(f flight) any { return f.Callsign },
+	"arrival":  func(f flight) any { return f.Arrival },
+	"alt":      func(f flight) any { return f.Alt },
+	"speed":    func(f flight) any { return f.Speed },
+}
+
+func parse(t testing.TB, src string) *parser.Expression[flight] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[flight](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+func TestMatch(t *testing.T) {
+	subs := map[string]string{
+		"eq":      `arrival = "UUEE"`,
+		"in":      `arrival = "UUEE" or arrival = "UUDD" or arrival = "UUWW"`,
+		"range":   `alt >= 10000 and alt < 30000`,
+		"mixed":   `callsign =~ "^AFL" and alt > 20000`,
+		"scan":    `callsign !~ "^AFL"`,
+		"strings": `callsign >= "B" and callsign < "C"`,
+		"speed":   `speed > 250`,
+	}
+
+	e := New(accessors)
+	for id, src := range subs {
+		if err := e.Add(id, parse(t, src)); err != nil {
+			t.Fatalf("error adding %s: %v", id, err)
+		}
+	}
+
+	cases := []struct {
+		model    flight
+		expected []string
+	}{
+		{flight{"AFL123", "UUEE", 35000, 450}, []string{"eq", "in", "mixed", "speed"}},
+		{flight{"AFL123", "UUDD", 15000, 250}, []string{"in", "range"}},
+		{flight{"BAW12", "EGLL", 10000, "fast"}, []string{"range", "scan", "strings"}},
+		{flight{"SBI1", "UUWW", 29999, nil}, []string{"in", "range", "scan"}},
+	}
+
+	for _, tc := range cases {
+		ids := e.Match(tc.model)
+		if !reflect.DeepEqual(ids, tc.expected) {
+			t.Errorf("%+v matched %v, expected %v", tc.model, ids, tc.expected)
+		}
+	}
+
+	if !e.Remove("in") || e.Remove("in") {
+		t.Errorf("in should be removed exactly once")
+	}
+	ids := e.Match(flight{"AFL123", "UUDD", 15000, 250})
+	if !reflect.DeepEqual(ids, []string{"range"}) {
+		t.Errorf("after removal matched %v, expected [range]", ids)
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	e := New(accessors)
+	if err := e.Add("a", parse(t, `alt > 1`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		id  string
+		src string
+		err string
+	}{
+		{"a", `alt > 2`, "subscription a already exists"},
+		{"b", `heading > 2`, "unknown identifier heading at line 1 pos 1"},
+		{"c", `callsign =~ "("`, "invalid regular expression"},
+	}
+	for _, tc := range cases {
+		err := e.Add(tc.id, parse(t, tc.src))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("adding %s got %v, expected %s", tc.src, err, tc.err)
+		}
+	}
+	if e.Len() != 1 {
+		t.Errorf("got %d subscriptions, expected 1", e.Len())
+	}
+}
+
+func randomCondition(r *rand.Rand) string {
+	ops := []string{"=", "!=", "<", "<=", ">", ">="}
+	op := ops[r.Intn(len(ops))]
+	switch r.Intn(4) {
+	case 0:
+		return fmt.Sprintf("alt %s %d", op, r.Intn(5)*1000)
+	case 1:
+		return fmt.Sprintf("speed %s %d", op, r.Intn(5)*100)
+	case 2:
+		return fmt.Sprintf(`arrival %s "%c"`, op, 'A'+r.Intn(5))
+	default:
+		if r.Intn(2) == 0 {
+			return fmt.Sprintf(`callsign =~ "^%c"`, 'A'+r.Intn(5))
+		}
+		return fmt.Sprintf(`callsign %s "%c"`, op, 'A'+r.Intn(5))
+	}
+}
+
+func randomExpression(r *rand.Rand, depth int) string {
+	n := 1 + r.Intn(3)
+	parts := make([]string, n)
+	for i := range parts {
+		if depth > 0 && r.Intn(3) == 0 {
+			parts[i] = "(" + randomExpression(r, depth-1) + ")"
+		} else {
+			parts[i] = randomCondition(r)
+		}
+	}
+
+	var sb strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteString([]string{" and ", " or "}[r.Intn(2)])
+		}
+		sb.WriteString(part)
+	}
+	return sb.String()
+}
+
+func randomFlight(r *rand.Rand) flight {
+	speeds := []any{nil, "fast", 100, 250.5, uint16(300), 400}
+	return flight{
+		Callsign: string(rune('A'+r.Intn(5))) + "X",
+		Arrival:  string(rune('A' + r.Intn(5))),
+		Alt:      r.Intn(5) * 1000,
+		Speed:    speeds[r.Intn(len(speeds))],
+	}
+}
+
+// TestMatchEquivalent compares the engine to evaluating every subscription
+// while subscriptions come and go
+func TestMatchEquivalent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	e := New(accessors)
+	programs := make(map[string]*parser.Program[flight])
+
+	for round := 0; round < 50; round++ {
+		for i := 0; i < 20; i++ {
+			id := fmt.Sprintf("s%d", r.Intn(100))
+			if _, found := programs[id]; found {
+				e.Remove(id)
+				delete(programs, id)
+				continue
+			}
+
+			expr := parse(t, randomExpression(r, 2))
+			program, err := expr.Compile(match.Compiler(accessors))
+			if err != nil {
+				t.Fatalf("error compiling %s: %v", expr, err)
+			}
+			if err := e.Add(id, expr); err != nil {
+				t.Fatalf("error adding %s: %v", expr, err)
+			}
+			programs[id] = program
+		}
+
+		for i := 0; i < 20; i++ {
+			model := randomFlight(r)
+			var expected []string
+			for id, program := range programs {
+				if program.Evaluate(model) {
+					expected = append(expected, id)
+				}
+			}
+			sort.Strings(expected)
+
+			ids := e.Match(model)
+			if !reflect.DeepEqual(ids, expected) {
+				t.Fatalf("%+v matched %v, expected %v", model, ids, expected)
+			}
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	e := New(accessors)
+	for i := 0; i < 1000; i++ {
+		if err := e.Add(fmt.Sprintf("s%d", i), parse(b, randomExpression(r, 1))); err != nil {
+			b.Fatal(err)
+		}
+	}
+	model := randomFlight(r)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.Match(model)
+	}
+}
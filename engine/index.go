@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"math"
+	"sort"
+
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// predKey identifies equivalent conditions, value is either float64 or
+	// string
+	predKey struct {
+		field string
+		op    parser.OperatorType
+		value any
+	}
+
+	// predicate is an indexed condition shared by all the subscriptions
+	// containing an equivalent one
+	predicate struct {
+		id  int
+		key predKey
+		// handles of the subscriptions notified when the predicate holds
+		subs map[int]struct{}
+	}
+
+	// rangeKey separates range predicates by operator and bound type,
+	// a number bound never matches a string field and vice versa
+	rangeKey struct {
+		op     parser.OperatorType
+		string bool
+	}
+
+	rangeEntry struct {
+		bound any
+		pred  int
+	}
+
+	// fieldIndex finds the predicates on a single field satisfied by
+	// a value: equality predicates are hashed by their value, range
+	// predicates are kept sorted by their bound so the satisfied ones form
+	// a contiguous run found with a binary search
+	fieldIndex struct {
+		eq     map[any][]int
+		ranges map[rangeKey][]rangeEntry
+	}
+)
+
+var indexable = map[parser.OperatorType]bool{
+	parser.Equals:         true,
+	parser.Less:           true,
+	parser.LessOrEqual:    true,
+	parser.Greater:        true,
+	parser.GreaterOrEqual: true,
+}
+
+func newFieldIndex() *fieldIndex {
+	return &fieldIndex{
+		eq:     make(map[any][]int),
+		ranges: make(map[rangeKey][]rangeEntry),
+	}
+}
+
+// less compares two values of the same type
+func less(a, b any) bool {
+	if af, ok := a.(float64); ok {
+		return af < b.(float64)
+	}
+	return a.(string) < b.(string)
+}
+
+func keyOf(p *predicate) rangeKey {
+	_, str := p.key.value.(string)
+	return rangeKey{p.key.op, str}
+}
+
+func (fi *fieldIndex) add(p *predicate) {
+	value := p.key.value
+	if p.key.op == parser.Equals {
+		fi.eq[value] = append(fi.eq[value], p.id)
+		return
+	}
+
+	rk := keyOf(p)
+	entries := fi.ranges[rk]
+	idx := sort.Search(len(entries), func(i int) bool { return !less(entries[i].bound, value) })
+	entries = append(entries, rangeEntry{})
+	copy(entries[idx+1:], entries[idx:])
+	entries[idx] = rangeEntry{value, p.id}
+	fi.ranges[rk] = entries
+}
+
+func (fi *fieldIndex) remove(p *predicate) {
+	value := p.key.value
+	if p.key.op == parser.Equals {
+		ids := fi.eq[value]
+		for i, id := range ids {
+			if id == p.id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(fi.eq, value)
+		} else {
+			fi.eq[value] = ids
+		}
+		return
+	}
+
+	rk := keyOf(p)
+	entries := fi.ranges[rk]
+	for i, e := range entries {
+		if e.pred == p.id {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(fi.ranges, rk)
+	} else {
+		fi.ranges[rk] = entries
+	}
+}
+
+func (fi *fieldIndex) empty() bool {
+	return len(fi.eq) == 0 && len(fi.ranges) == 0
+}
+
+// normalize converts a field value to the type predicates are indexed
+// with, ok is false if no indexed predicate can hold for the value
+func normalize(v any) (any, bool) {
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	f, ok := match.ToFloat(v)
+	if !ok || math.IsNaN(f) {
+		return nil, false
+	}
+	return f, true
+}
+
+// lookup calls fn for every predicate satisfied by the field value
+func (fi *fieldIndex) lookup(v any, fn func(pred int)) {
+	v, ok := normalize(v)
+	if !ok {
+		return
+	}
+
+	for _, id := range fi.eq[v] {
+		fn(id)
+	}
+
+	_, str := v.(string)
+	for rk, entries := range fi.ranges {
+		if rk.string != str {
+			continue
+		}
+
+		// entries are sorted by bound, so the predicates holding for v are
+		// either a prefix or a suffix of them
+		var run []rangeEntry
+		switch rk.op {
+		case parser.Greater:
+			// v > bound
+			run = entries[:sort.Search(len(entries), func(i int) bool { return !less(entries[i].bound, v) })]
+		case parser.GreaterOrEqual:
+			run = entries[:sort.Search(len(entries), func(i int) bool { return less(v, entries[i].bound) })]
+		case parser.Less:
+			run = entries[sort.Search(len(entries), func(i int) bool { return less(v, entries[i].bound) }):]
+		case parser.LessOrEqual:
+			run = entries[sort.Search(len(entries), func(i int) bool { return !less(entries[i].bound, v) }):]
+		}
+
+		for _, e := range run {
+			fn(e.pred)
+		}
+	}
+}
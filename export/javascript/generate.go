@@ -0,0 +1,231 @@
+package javascript
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Options configure the generated function
+	Options struct {
+		// Fields maps identifiers to dotted property paths of the model
+		// object, i.e. "flight_plan.arrival". Identifiers missing from
+		// the map are rejected.
+		Fields map[string]string
+	}
+
+	// Warning marks a condition which may behave differently in javascript
+	Warning struct {
+		Token   *lexer.Token
+		Message string
+	}
+
+	// Result holds the generated source and the divergence warnings
+	Result struct {
+		// Source is a javascript expression evaluating to a function
+		// which takes a model object and returns a boolean
+		Source   string
+		Warnings []Warning
+	}
+
+	generator[T any] struct {
+		opts       Options
+		setup      []string
+		warnings   []Warning
+		compareSet bool
+	}
+)
+
+// compareFunc orders strings by code points which is the same as the byte
+// order of their UTF-8 encoding used in Go, while javascript operators
+// compare UTF-16 code units
+const compareFunc = `  function compare(a, b) {
+    let i = 0, j = 0;
+    while (i < a.length && j < b.length) {
+      const x = a.codePointAt(i), y = b.codePointAt(j);
+      if (x !== y) return x < y ? -1 : 1;
+      i += x > 0xffff ? 2 : 1;
+      j += y > 0xffff ? 2 : 1;
+    }
+    return (i < a.length) - (j < b.length);
+  }`
+
+var jsOperators = map[parser.OperatorType]string{
+	parser.Equals:         "===",
+	parser.NotEquals:      "===",
+	parser.Less:           "<",
+	parser.LessOrEqual:    "<=",
+	parser.Greater:        ">",
+	parser.GreaterOrEqual: ">=",
+}
+
+func (w Warning) String() string {
+	if w.Token == nil {
+		return w.Message
+	}
+	return fmt.Sprintf("%s at line %d pos %d", w.Message, w.Token.Line, w.Token.Position)
+}
+
+func jsString(s string) string {
+	// JSON strings are valid javascript string literals
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// Generate emits a self-contained javascript function evaluating the
+// expression with the semantics of the match package, so it agrees with
+// Evaluate of a program compiled with match.Compiler. Regular expressions
+// are translated to keep the Go semantics, Warnings list the ones prone to
+// catastrophic backtracking which RE2 doesn't suffer from.
+func Generate[T any](e *parser.Expression[T], opts Options) (*Result, error) {
+	g := &generator[T]{opts: opts}
+	body, err := g.expression(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(function () {\n")
+	sb.WriteString(`  "use strict";` + "\n")
+	if g.compareSet {
+		sb.WriteString(compareFunc + "\n")
+	}
+	for _, line := range g.setup {
+		sb.WriteString("  " + line + "\n")
+	}
+	sb.WriteString("  return function (m) {\n")
+	sb.WriteString("    return " + body + ";\n")
+	sb.WriteString("  };\n")
+	sb.WriteString("})()")
+
+	return &Result{Source: sb.String(), Warnings: g.warnings}, nil
+}
+
+func (g *generator[T]) expression(e *parser.Expression[T]) (string, error) {
+	var left string
+	var err error
+
+	if e.Left.Condition != nil {
+		left, err = g.condition(e.Left.Condition)
+	} else {
+		left, err = g.expression(e.Left.Grouping.Expression)
+		if err == nil && e.Left.Grouping.Expression.Right != nil {
+			left = "(" + left + ")"
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if e.Right == nil {
+		return left, nil
+	}
+
+	right, err := g.expression(e.Right)
+	if err != nil {
+		return "", err
+	}
+	if e.Right.Right != nil && e.Right.Operator.Type != e.Operator.Type {
+		right = "(" + right + ")"
+	}
+
+	switch e.Operator.Type {
+	case parser.And:
+		return left + " && " + right, nil
+	case parser.Or:
+		return left + " || " + right, nil
+	default:
+		return "", lexer.ErrorAt(e.Operator.Token, "unsupported combine operator %s", e.Operator.Type)
+	}
+}
+
+func (g *generator[T]) accessor(ident *parser.Identifier) (string, error) {
+	path, found := g.opts.Fields[ident.Name]
+	if !found {
+		return "", lexer.ErrorAt(ident.Token, "identifier %s has no field mapping", ident.Name)
+	}
+
+	acc := "m"
+	for _, key := range strings.Split(path, ".") {
+		acc += "?.[" + jsString(key) + "]"
+	}
+	return acc, nil
+}
+
+func (g *generator[T]) condition(c *parser.Condition[T]) (string, error) {
+	acc, err := g.accessor(c.Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	pred, err := g.predicate(c)
+	if err != nil {
+		return "", err
+	}
+
+	name := "c" + strconv.Itoa(len(g.setup))
+	g.setup = append(g.setup, "const "+name+" = (v) => "+pred+";")
+	return name + "(" + acc + ")", nil
+}
+
+func (g *generator[T]) predicate(c *parser.Condition[T]) (string, error) {
+	op := c.Operator.Type
+	if c.Value.IsPlaceholder() {
+		return "", lexer.ErrorAt(c.Value.Token, "unbound parameter %s", *c.Value.Placeholder)
+	}
+
+	if op == parser.Matches || op == parser.NotMatches {
+		pattern, err := c.Value.GetUnquotedStringValue()
+		if err != nil {
+			return "", lexer.ErrorAt(c.Value.Token, "regular expression must be a string")
+		}
+		re, backtracks, err := translateRegexp(pattern)
+		if err != nil {
+			return "", lexer.ErrorAt(c.Value.Token, "invalid regular expression: %v", err)
+		}
+		if backtracks {
+			g.warnings = append(g.warnings, Warning{
+				c.Value.Token,
+				"nested repetition may backtrack exponentially in javascript",
+			})
+		}
+
+		pred := `typeof v === "string" && ` + re + ".test(v)"
+		if op == parser.NotMatches {
+			return "!(" + pred + ")", nil
+		}
+		return pred, nil
+	}
+
+	jsOp, found := jsOperators[op]
+	if !found {
+		return "", lexer.ErrorAt(c.Operator.Token, "unsupported operator %s", op)
+	}
+
+	var pred string
+	if c.Value.IsFloat() {
+		value := strconv.FormatFloat(*c.Value.Number, 'g', -1, 64)
+		pred = `typeof v === "number" && v ` + jsOp + " " + value
+	} else {
+		str, err := c.Value.GetUnquotedStringValue()
+		if err != nil {
+			return "", lexer.ErrorAt(c.Value.Token, "invalid string value")
+		}
+		if op == parser.Equals || op == parser.NotEquals {
+			pred = `typeof v === "string" && v === ` + jsString(str)
+		} else {
+			g.compareSet = true
+			pred = `typeof v === "string" && compare(v, ` + jsString(str) + ") " + jsOp + " 0"
+		}
+	}
+
+	if op == parser.NotEquals {
+		return "!(" + pred + ")", nil
+	}
+	return pred, nil
+}
@@ -0,0 +1,174 @@
+package javascript
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+var fields = map[string]string{
+	"callsign": "callsign",
+	"alt":      "altitude",
+	"arrival":  "flight_plan.arrival",
+}
+
+var records = []string{
+	`{"callsign": "AFL123", "altitude": 35000, "flight_plan": {"arrival": "UUEE"}}`,
+	`{"callsign": "afl7", "altitude": 900.5}`,
+	`{"callsign": "SU\n100", "altitude": "high", "flight_plan": null}`,
+	`{"callsign": "𝔘X", "altitude": 0, "flight_plan": {"arrival": "Ａ"}}`,
+	`{"callsign": "KLM", "flight_plan": {"arrival": 5}}`,
+	`{"callsign": "a\rb"}`,
+	`{}`,
+}
+
+var expressions = []string{
+	`callsign = "AFL123"`,
+	`callsign != "AFL123"`,
+	`alt > 1000 and callsign =~ "^AFL" or arrival = "UUEE"`,
+	`(alt >= 900.5 or alt < 0) and arrival != "UUEE"`,
+	`callsign =~ "(?i)^afl\d+$"`,
+	`callsign =~ "(?i)k"`,
+	`callsign !~ "^SU.100$"`,
+	`callsign =~ "(?m)^100$"`,
+	`callsign =~ "a.b"`,
+	`callsign > "￿" or arrival < "B"`,
+	`arrival >= "UUEE" and arrival <= "UUEE"`,
+	`callsign =~ "[[:upper:]]{3}\pN"`,
+	`callsign =~ "\QSU\E|^.X$"`,
+}
+
+func parse(t *testing.T, src string) *parser.Expression[map[string]any] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[map[string]any](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+func accessors() map[string]match.Accessor[map[string]any] {
+	acc := make(map[string]match.Accessor[map[string]any])
+	for ident, path := range fields {
+		keys := strings.Split(path, ".")
+		acc[ident] = func(model map[string]any) any {
+			var v any = model
+			for _, key := range keys {
+				m, ok := v.(map[string]any)
+				if !ok {
+					return nil
+				}
+				v = m[key]
+			}
+			return v
+		}
+	}
+	return acc
+}
+
+func TestGenerate(t *testing.T) {
+	res, err := Generate(parse(t, `alt > 1000 and callsign !~ "^AFL"`), Options{Fields: fields})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := strings.Join([]string{
+		`(function () {`,
+		`  "use strict";`,
+		`  const c0 = (v) => typeof v === "number" && v > 1000;`,
+		`  const c1 = (v) => !(typeof v === "string" && /^AFL/u.test(v));`,
+		`  return function (m) {`,
+		`    return c0(m?.["altitude"]) && c1(m?.["callsign"]);`,
+		`  };`,
+		`})()`,
+	}, "\n")
+	if res.Source != exp {
+		t.Errorf("invalid source, got\n%s\nexpected\n%s", res.Source, exp)
+	}
+}
+
+func TestGenerateWarnings(t *testing.T) {
+	res, err := Generate(parse(t, `callsign =~ "(a+)+$"`), Options{Fields: fields})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Warnings) != 1 {
+		t.Fatalf("expected a backtracking warning, got %v", res.Warnings)
+	}
+	exp := "nested repetition may backtrack exponentially in javascript at line 1 pos 13"
+	if res.Warnings[0].String() != exp {
+		t.Errorf("invalid warning, got %s, expected %s", res.Warnings[0], exp)
+	}
+}
+
+// TestGenerateMatchesEvaluate runs the generated functions with node and
+// compares the results with the Go evaluation
+func TestGenerateMatchesEvaluate(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not available")
+	}
+
+	models := make([]map[string]any, len(records))
+	for i, rec := range records {
+		if err := json.Unmarshal([]byte(rec), &models[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var script strings.Builder
+	script.WriteString("const records = [" + strings.Join(records, ",") + "];\n")
+	script.WriteString("const fns = [\n")
+
+	expected := make([][]bool, len(expressions))
+	for i, src := range expressions {
+		expr := parse(t, src)
+		p, err := expr.Compile(match.Compiler(accessors()))
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", src, err)
+		}
+		for _, model := range models {
+			expected[i] = append(expected[i], p.Evaluate(model))
+		}
+
+		res, err := Generate(expr, Options{Fields: fields})
+		if err != nil {
+			t.Fatalf("error generating %s: %v", src, err)
+		}
+		script.WriteString(res.Source + ",\n")
+	}
+	script.WriteString("];\n")
+	script.WriteString("console.log(JSON.stringify(fns.map((fn) => records.map(fn))));\n")
+
+	cmd := exec.Command(node, "-")
+	cmd.Stdin = strings.NewReader(script.String())
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("error running node: %v\n%s", err, script.String())
+	}
+
+	var actual [][]bool
+	if err := json.Unmarshal(out, &actual); err != nil {
+		t.Fatalf("invalid node output %s: %v", out, err)
+	}
+
+	for i := range expressions {
+		for j := range records {
+			if actual[i][j] != expected[i][j] {
+				t.Errorf(
+					"%s on record %d: javascript returns %v, Go returns %v",
+					expressions[i], j+1, actual[i][j], expected[i][j],
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package javascript
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// translateRegexp rewrites a Go regular expression into the source of an
+// equivalent javascript one. The expression is rebuilt from the parsed
+// syntax tree with the unicode flag so character classes, case folding,
+// and line anchors keep the Go semantics. The second value tells whether
+// the expression nests unbounded repetitions which may backtrack
+// exponentially in javascript while RE2 guarantees linear time.
+func translateRegexp(pattern string) (string, bool, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("/")
+	writeRegexp(&sb, re)
+	sb.WriteString("/u")
+	return sb.String(), nestedRepeat(re, false), nil
+}
+
+func isUnbounded(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return true
+	case syntax.OpRepeat:
+		return re.Max == -1
+	}
+	return false
+}
+
+func nestedRepeat(re *syntax.Regexp, inRepeat bool) bool {
+	unbounded := isUnbounded(re)
+	if unbounded && inRepeat {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if nestedRepeat(sub, inRepeat || unbounded) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeRune(sb *strings.Builder, r rune) {
+	if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '_') {
+		sb.WriteRune(r)
+		return
+	}
+	sb.WriteString(`\u{` + strconv.FormatInt(int64(r), 16) + `}`)
+}
+
+func writeClass(sb *strings.Builder, ranges []rune) {
+	sb.WriteString("[")
+	for i := 0; i+1 < len(ranges); i += 2 {
+		writeRune(sb, ranges[i])
+		if ranges[i+1] != ranges[i] {
+			sb.WriteString("-")
+			writeRune(sb, ranges[i+1])
+		}
+	}
+	sb.WriteString("]")
+}
+
+func writeLiteral(sb *strings.Builder, r rune, foldCase bool) {
+	if !foldCase {
+		writeRune(sb, r)
+		return
+	}
+
+	orbit := []rune{r}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		orbit = append(orbit, f)
+	}
+	if len(orbit) == 1 {
+		writeRune(sb, r)
+		return
+	}
+
+	sb.WriteString("[")
+	for _, f := range orbit {
+		writeRune(sb, f)
+	}
+	sb.WriteString("]")
+}
+
+func writeGroup(sb *strings.Builder, re *syntax.Regexp) {
+	sb.WriteString("(?:")
+	writeRegexp(sb, re)
+	sb.WriteString(")")
+}
+
+func writeRegexp(sb *strings.Builder, re *syntax.Regexp) {
+	nonGreedy := ""
+	if re.Flags&syntax.NonGreedy != 0 {
+		nonGreedy = "?"
+	}
+
+	switch re.Op {
+	case syntax.OpNoMatch:
+		sb.WriteString("[]")
+	case syntax.OpEmptyMatch:
+		sb.WriteString("(?:)")
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			writeLiteral(sb, r, re.Flags&syntax.FoldCase != 0)
+		}
+	case syntax.OpCharClass:
+		writeClass(sb, re.Rune)
+	case syntax.OpAnyCharNotNL:
+		// javascript dot also excludes \r and the unicode line separators
+		sb.WriteString(`[^\n]`)
+	case syntax.OpAnyChar:
+		sb.WriteString(`[\s\S]`)
+	case syntax.OpBeginLine:
+		sb.WriteString(`(?<=^|\n)`)
+	case syntax.OpEndLine:
+		sb.WriteString(`(?=\n|$)`)
+	case syntax.OpBeginText:
+		sb.WriteString("^")
+	case syntax.OpEndText:
+		sb.WriteString("$")
+	case syntax.OpWordBoundary:
+		sb.WriteString(`\b`)
+	case syntax.OpNoWordBoundary:
+		sb.WriteString(`\B`)
+	case syntax.OpCapture:
+		writeGroup(sb, re.Sub[0])
+	case syntax.OpStar:
+		writeGroup(sb, re.Sub[0])
+		sb.WriteString("*" + nonGreedy)
+	case syntax.OpPlus:
+		writeGroup(sb, re.Sub[0])
+		sb.WriteString("+" + nonGreedy)
+	case syntax.OpQuest:
+		writeGroup(sb, re.Sub[0])
+		sb.WriteString("?" + nonGreedy)
+	case syntax.OpRepeat:
+		writeGroup(sb, re.Sub[0])
+		if re.Max == -1 {
+			sb.WriteString(fmt.Sprintf("{%d,}", re.Min))
+		} else {
+			sb.WriteString(fmt.Sprintf("{%d,%d}", re.Min, re.Max))
+		}
+		sb.WriteString(nonGreedy)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeRegexp(sb, sub)
+		}
+	case syntax.OpAlternate:
+		sb.WriteString("(?:")
+		for i, sub := range re.Sub {
+			if i > 0 {
+				sb.WriteString("|")
+			}
+			writeRegexp(sb, sub)
+		}
+		sb.WriteString(")")
+	}
+}
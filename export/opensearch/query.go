@@ -0,0 +1,137 @@
+package opensearch
+
+import (
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Query is a query DSL clause ready to be encoded as JSON
+	Query map[string]any
+
+	// Options configure the translation of an expression
+	Options struct {
+		// Fields maps identifiers to index field names, identifiers missing
+		// from the map are rejected. Use keyword fields for strings as term
+		// and regexp queries are not analyzed.
+		Fields map[string]string
+	}
+
+	translator[T any] struct {
+		opts Options
+	}
+)
+
+var rangeOperators = map[parser.OperatorType]string{
+	parser.Less:           "lt",
+	parser.LessOrEqual:    "lte",
+	parser.Greater:        "gt",
+	parser.GreaterOrEqual: "gte",
+}
+
+// Translate turns the expression into a bool/term/range/regexp query.
+// Chains of the same combine operator are flattened into a single bool
+// query, and conditions go to the filter context as they don't need scoring.
+func Translate[T any](e *parser.Expression[T], opts Options) (Query, error) {
+	t := &translator[T]{opts}
+	return t.expression(e)
+}
+
+func (t *translator[T]) expression(e *parser.Expression[T]) (Query, error) {
+	left, err := t.operand(e.Left)
+	if err != nil {
+		return nil, err
+	}
+	if e.Right == nil {
+		return left, nil
+	}
+
+	op := e.Operator.Type
+	clauses := []Query{left}
+	for right := e.Right; ; right = right.Right {
+		if right.Right == nil || right.Operator.Type != op {
+			// the rest of the chain binds to the right as a whole
+			q, err := t.expression(right)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, q)
+			break
+		}
+
+		q, err := t.operand(right.Left)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, q)
+	}
+
+	switch op {
+	case parser.And:
+		return Query{"bool": Query{"filter": clauses}}, nil
+	case parser.Or:
+		return Query{"bool": Query{"should": clauses, "minimum_should_match": 1}}, nil
+	default:
+		return nil, lexer.ErrorAt(e.Operator.Token, "unsupported combine operator %s", op)
+	}
+}
+
+func (t *translator[T]) operand(le *parser.LeftExpression[T]) (Query, error) {
+	if le.Condition != nil {
+		return t.condition(le.Condition)
+	}
+	return t.expression(le.Grouping.Expression)
+}
+
+func (t *translator[T]) condition(c *parser.Condition[T]) (Query, error) {
+	field, found := t.opts.Fields[c.Identifier.Name]
+	if !found {
+		return nil, lexer.ErrorAt(c.Identifier.Token, "identifier %s has no field mapping", c.Identifier.Name)
+	}
+
+	if c.Value.IsPlaceholder() {
+		return nil, lexer.ErrorAt(c.Value.Token, "unbound parameter %s", *c.Value.Placeholder)
+	}
+
+	var value any
+	var err error
+	if c.Value.IsFloat() {
+		value = *c.Value.Number
+	} else {
+		value, err = c.Value.GetUnquotedStringValue()
+		if err != nil {
+			return nil, lexer.ErrorAt(c.Value.Token, "invalid string value")
+		}
+	}
+
+	switch c.Operator.Type {
+	case parser.Equals:
+		return Query{"term": Query{field: value}}, nil
+	case parser.NotEquals:
+		return not(Query{"term": Query{field: value}}), nil
+	case parser.Matches, parser.NotMatches:
+		str, ok := value.(string)
+		if !ok {
+			return nil, lexer.ErrorAt(c.Value.Token, "regular expression must be a string")
+		}
+		re, err := luceneRegexp(str)
+		if err != nil {
+			return nil, lexer.ErrorAt(c.Value.Token, "%v", err)
+		}
+		q := Query{"regexp": Query{field: Query{"value": re}}}
+		if c.Operator.Type == parser.NotMatches {
+			return not(q), nil
+		}
+		return q, nil
+	default:
+		rangeOp, found := rangeOperators[c.Operator.Type]
+		if !found {
+			return nil, lexer.ErrorAt(c.Operator.Token, "unsupported operator %s", c.Operator.Type)
+		}
+		return Query{"range": Query{field: Query{rangeOp: value}}}, nil
+	}
+}
+
+func not(q Query) Query {
+	return Query{"bool": Query{"must_not": []Query{q}}}
+}
@@ -0,0 +1,125 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var fields = map[string]string{
+	"callsign": "callsign.keyword",
+	"alt":      "altitude",
+	"arrival":  "flight_plan.arrival",
+}
+
+func translate(src string) (Query, error) {
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := parser.Parse[any](tokens)
+	if err != nil {
+		return nil, err
+	}
+	return Translate(expr, Options{Fields: fields})
+}
+
+func TestTranslateGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.lee")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		src, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q, err := translate(string(src))
+		if err != nil {
+			t.Errorf("unexpected error translating %s: %v", input, err)
+			continue
+		}
+
+		result, err := json.MarshalIndent(q, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, '\n')
+
+		golden := strings.TrimSuffix(input, ".lee") + ".golden.json"
+		if *update {
+			if err := os.WriteFile(golden, result, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		exp, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != string(exp) {
+			t.Errorf("invalid query for %s, got\n%s\nexpected\n%s", input, result, exp)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	cases := map[string]string{
+		`callsign = "A" or unknown = 1`: "identifier unknown has no field mapping at line 1 pos 19",
+		`callsign =~ 5`:                 "regular expression must be a string at line 1 pos 13",
+		`callsign =~ "\bAFL"`:           `regular expression \bAFL has word boundaries unsupported by lucene at line 1 pos 13`,
+		`callsign =~ "(?m)^AFL"`:        `regular expression (?m)^AFL has line anchors unsupported by lucene at line 1 pos 13`,
+		`callsign =~ "A^B"`:             `regular expression A^B has an anchor lucene can't express, only the edges may be anchored at line 1 pos 13`,
+	}
+
+	for input, exp := range cases {
+		_, err := translate(input)
+		if err == nil {
+			t.Errorf("%s should fail", input)
+			continue
+		}
+		if err.Error() != exp {
+			t.Errorf("should throw %v, but throws %v", exp, err)
+		}
+	}
+}
+
+func TestLuceneRegexp(t *testing.T) {
+	cases := map[string]string{
+		`AFL`:       `.*AFL.*`,
+		`^AFL$`:     `AFL`,
+		`^AFL|^SU`:  `(AFL.*|SU.*)`,
+		`^AFL|SU$`:  `(AFL.*|.*SU)`,
+		`^(AFL|SU)`: `(AFL|SU).*`,
+		`\d{3,}`:    `.*[0-9]{3,}.*`,
+		`(?i)afl`:   `.*[Aa][Ff][Ll].*`,
+		`a.b`:       ".*a[\x00-\t\x0b-\U0010ffff]b.*",
+		`(ab)+c?`:   `.*(ab)+c?.*`,
+		`a@b|"c"`:   `(.*a\@b.*|.*\"c\".*)`,
+		``:          `.*`,
+		`^`:         `.*`,
+		`x*?$`:      `.*x*`,
+		`[^a-z]+`:   ".*[\x00-\\`\\{-\U0010ffff]+.*",
+	}
+	for pattern, exp := range cases {
+		result, err := luceneRegexp(pattern)
+		if err != nil {
+			t.Errorf("unexpected error converting %s: %v", pattern, err)
+			continue
+		}
+		if result != exp {
+			t.Errorf("%s got %q, expected %q", pattern, result, exp)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package opensearch
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// luceneRegexp converts a Go regular expression which matches anywhere in
+// the string into a lucene one which always matches the whole term. The
+// expression is rebuilt from the parsed syntax tree, so Go shorthands like
+// \d and case folding become explicit classes. Anchors are only allowed at
+// the edges of the expression or of its top level alternatives, line and
+// word boundaries have no lucene counterpart and are rejected.
+func luceneRegexp(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := writeTerm(&sb, re); err != nil {
+		return "", fmt.Errorf("regular expression %s %w", pattern, err)
+	}
+	return sb.String(), nil
+}
+
+var repeatOperators = map[syntax.Op]string{
+	syntax.OpStar:  "*",
+	syntax.OpPlus:  "+",
+	syntax.OpQuest: "?",
+}
+
+func isBegin(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpBeginText
+}
+
+func isEnd(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpEndText
+}
+
+// writeTerm writes the expression matching the whole terms containing
+// a match of re, unanchored edges are padded with .*
+func writeTerm(sb *strings.Builder, re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpCapture:
+		return writeTerm(sb, re.Sub[0])
+	case syntax.OpAlternate:
+		sb.WriteString("(")
+		for i, sub := range re.Sub {
+			if i > 0 {
+				sb.WriteString("|")
+			}
+			if err := writeTerm(sb, sub); err != nil {
+				return err
+			}
+		}
+		sb.WriteString(")")
+		return nil
+	}
+
+	subs := []*syntax.Regexp{re}
+	switch re.Op {
+	case syntax.OpConcat:
+		subs = re.Sub
+	case syntax.OpEmptyMatch:
+		subs = nil
+	}
+	begin := len(subs) > 0 && isBegin(subs[0])
+	if begin {
+		subs = subs[1:]
+	}
+	end := len(subs) > 0 && isEnd(subs[len(subs)-1])
+	if end {
+		subs = subs[:len(subs)-1]
+	}
+
+	if !begin {
+		sb.WriteString(".*")
+	}
+	for _, sub := range subs {
+		if err := writeRegexp(sb, sub, true); err != nil {
+			return err
+		}
+	}
+	// a single .* is enough for an empty expression
+	if !end && (begin || len(subs) > 0) {
+		sb.WriteString(".*")
+	}
+	return nil
+}
+
+func writeRune(sb *strings.Builder, r rune) {
+	// every escaped character is literal in lucene, the ascii punctuation
+	// holds all its operators
+	if r > ' ' && r < unicode.MaxASCII && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		sb.WriteString(`\`)
+	}
+	sb.WriteRune(r)
+}
+
+func writeClass(sb *strings.Builder, ranges []rune) {
+	sb.WriteString("[")
+	for i := 0; i+1 < len(ranges); i += 2 {
+		writeRune(sb, ranges[i])
+		if ranges[i+1] != ranges[i] {
+			sb.WriteString("-")
+			writeRune(sb, ranges[i+1])
+		}
+	}
+	sb.WriteString("]")
+}
+
+func writeLiteral(sb *strings.Builder, r rune, foldCase bool) {
+	orbit := []rune{r}
+	if foldCase {
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			orbit = append(orbit, f)
+		}
+	}
+	if len(orbit) == 1 {
+		writeRune(sb, r)
+		return
+	}
+
+	sb.WriteString("[")
+	for _, f := range orbit {
+		writeRune(sb, f)
+	}
+	sb.WriteString("]")
+}
+
+// writeGroup writes the operand of a repetition, single characters and
+// classes need no parentheses
+func writeGroup(sb *strings.Builder, re *syntax.Regexp) error {
+	for re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+	switch {
+	case re.Op == syntax.OpLiteral && len(re.Rune) == 1,
+		re.Op == syntax.OpCharClass, re.Op == syntax.OpAnyChar, re.Op == syntax.OpAnyCharNotNL:
+		return writeRegexp(sb, re, false)
+	}
+	sb.WriteString("(")
+	if err := writeRegexp(sb, re, false); err != nil {
+		return err
+	}
+	sb.WriteString(")")
+	return nil
+}
+
+// writeRegexp writes the expression in lucene syntax, alternations are
+// parenthesized inside a concatenation
+func writeRegexp(sb *strings.Builder, re *syntax.Regexp, inConcat bool) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		// the empty language, lucene operators are enabled in the query
+		sb.WriteString("#")
+	case syntax.OpEmptyMatch:
+		sb.WriteString(`""`)
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			writeLiteral(sb, r, re.Flags&syntax.FoldCase != 0)
+		}
+	case syntax.OpCharClass:
+		writeClass(sb, re.Rune)
+	case syntax.OpAnyCharNotNL:
+		writeClass(sb, []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune})
+	case syntax.OpAnyChar:
+		sb.WriteString(".")
+	case syntax.OpBeginText, syntax.OpEndText:
+		return fmt.Errorf("has an anchor lucene can't express, only the edges may be anchored")
+	case syntax.OpBeginLine, syntax.OpEndLine:
+		return fmt.Errorf("has line anchors unsupported by lucene")
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return fmt.Errorf("has word boundaries unsupported by lucene")
+	case syntax.OpCapture:
+		return writeGroup(sb, re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		// lucene always matches the whole term, so greediness doesn't
+		// change the result
+		if err := writeGroup(sb, re.Sub[0]); err != nil {
+			return err
+		}
+		sb.WriteString(repeatOperators[re.Op])
+	case syntax.OpRepeat:
+		if err := writeGroup(sb, re.Sub[0]); err != nil {
+			return err
+		}
+		if re.Max == -1 {
+			sb.WriteString(fmt.Sprintf("{%d,}", re.Min))
+		} else {
+			sb.WriteString(fmt.Sprintf("{%d,%d}", re.Min, re.Max))
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := writeRegexp(sb, sub, true); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		if inConcat {
+			sb.WriteString("(")
+		}
+		for i, sub := range re.Sub {
+			if i > 0 {
+				sb.WriteString("|")
+			}
+			if err := writeRegexp(sb, sub, true); err != nil {
+				return err
+			}
+		}
+		if inConcat {
+			sb.WriteString(")")
+		}
+	default:
+		return fmt.Errorf("has %s unsupported by lucene", re.Op)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+)
+
+type (
+	// Dialect is a set of hooks producing SQL syntax that differs
+	// between database engines
+	Dialect interface {
+		// Placeholder returns the bind parameter placeholder for
+		// the n-th argument, n starts from 1
+		Placeholder(n int) string
+		// QuoteIdentifier quotes a single column or table name
+		QuoteIdentifier(name string) string
+		// Match returns a condition matching column against the regular
+		// expression bound to placeholder, ok is false if the dialect has
+		// no regular expression support
+		Match(column string, placeholder string, negate bool) (cond string, ok bool)
+	}
+
+	postgres struct{}
+	sqlite   struct{}
+	mysql    struct{}
+)
+
+var (
+	// Postgres uses $n placeholders and the ~ operator for regular expressions
+	Postgres Dialect = postgres{}
+	// SQLite uses ? placeholders and the REGEXP operator which requires
+	// a regexp() function to be registered with the connection
+	SQLite Dialect = sqlite{}
+	// MySQL uses ? placeholders, backtick quoting and the REGEXP operator
+	MySQL Dialect = mysql{}
+)
+
+func quoteWith(name string, quote string) string {
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) QuoteIdentifier(name string) string {
+	return quoteWith(name, `"`)
+}
+
+func (postgres) Match(column string, placeholder string, negate bool) (string, bool) {
+	if negate {
+		return column + " !~ " + placeholder, true
+	}
+	return column + " ~ " + placeholder, true
+}
+
+func (sqlite) Placeholder(int) string {
+	return "?"
+}
+
+func (sqlite) QuoteIdentifier(name string) string {
+	return quoteWith(name, `"`)
+}
+
+func (sqlite) Match(column string, placeholder string, negate bool) (string, bool) {
+	if negate {
+		return column + " NOT REGEXP " + placeholder, true
+	}
+	return column + " REGEXP " + placeholder, true
+}
+
+func (mysql) Placeholder(int) string {
+	return "?"
+}
+
+func (mysql) QuoteIdentifier(name string) string {
+	return quoteWith(name, "`")
+}
+
+func (mysql) Match(column string, placeholder string, negate bool) (string, bool) {
+	if negate {
+		return column + " NOT REGEXP " + placeholder, true
+	}
+	return column + " REGEXP " + placeholder, true
+}
@@ -0,0 +1,156 @@
+package sql
+
+import (
+	"strings"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Options configure the translation of an expression
+	Options struct {
+		// Dialect defaults to Postgres
+		Dialect Dialect
+		// Columns maps identifiers to column names, a name may be
+		// qualified with a table as "table.column". Identifiers missing
+		// from the map are rejected.
+		Columns map[string]string
+	}
+
+	translator[T any] struct {
+		opts Options
+		args []any
+	}
+)
+
+var comparisons = map[parser.OperatorType]string{
+	parser.Equals:         "=",
+	parser.NotEquals:      "<>",
+	parser.Less:           "<",
+	parser.LessOrEqual:    "<=",
+	parser.Greater:        ">",
+	parser.GreaterOrEqual: ">=",
+}
+
+// Where translates the expression into a WHERE clause fragment. Values are
+// never inlined into the fragment, they're returned as bind arguments in
+// the order of their placeholders.
+func Where[T any](e *parser.Expression[T], opts Options) (string, []any, error) {
+	if opts.Dialect == nil {
+		opts.Dialect = Postgres
+	}
+
+	t := &translator[T]{opts: opts}
+	clause, err := t.expression(e)
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, t.args, nil
+}
+
+func (t *translator[T]) bind(value any) string {
+	t.args = append(t.args, value)
+	return t.opts.Dialect.Placeholder(len(t.args))
+}
+
+func (t *translator[T]) column(ident *parser.Identifier) (string, error) {
+	name, found := t.opts.Columns[ident.Name]
+	if !found {
+		return "", lexer.ErrorAt(ident.Token, "identifier %s has no column mapping", ident.Name)
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = t.opts.Dialect.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func (t *translator[T]) expression(e *parser.Expression[T]) (string, error) {
+	var left string
+	var err error
+
+	if e.Left.Condition != nil {
+		left, err = t.condition(e.Left.Condition)
+	} else {
+		left, err = t.grouping(e.Left.Grouping)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if e.Right == nil {
+		return left, nil
+	}
+
+	right, err := t.expression(e.Right)
+	if err != nil {
+		return "", err
+	}
+
+	// and/or share the same precedence and group to the right in the filter
+	// language while SQL binds AND tighter, so mixed chains need parentheses
+	if e.Right.Right != nil && e.Right.Operator.Type != e.Operator.Type {
+		right = "(" + right + ")"
+	}
+
+	switch e.Operator.Type {
+	case parser.And:
+		return left + " AND " + right, nil
+	case parser.Or:
+		return left + " OR " + right, nil
+	default:
+		return "", lexer.ErrorAt(e.Operator.Token, "unsupported combine operator %s", e.Operator.Type)
+	}
+}
+
+func (t *translator[T]) grouping(g *parser.Grouping[T]) (string, error) {
+	inner, err := t.expression(g.Expression)
+	if err != nil {
+		return "", err
+	}
+	if g.Expression.Right == nil {
+		return inner, nil
+	}
+	return "(" + inner + ")", nil
+}
+
+func (t *translator[T]) condition(c *parser.Condition[T]) (string, error) {
+	column, err := t.column(c.Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Value.IsPlaceholder() {
+		return "", lexer.ErrorAt(c.Value.Token, "unbound parameter %s", *c.Value.Placeholder)
+	}
+
+	var value any
+	if c.Value.IsFloat() {
+		value = *c.Value.Number
+	} else {
+		value, err = c.Value.GetUnquotedStringValue()
+		if err != nil {
+			return "", lexer.ErrorAt(c.Value.Token, "invalid string value")
+		}
+	}
+
+	switch c.Operator.Type {
+	case parser.Matches, parser.NotMatches:
+		if c.Value.IsFloat() {
+			return "", lexer.ErrorAt(c.Value.Token, "regular expression must be a string")
+		}
+		cond, ok := t.opts.Dialect.Match(column, t.bind(value), c.Operator.Type == parser.NotMatches)
+		if !ok {
+			return "", lexer.ErrorAt(c.Operator.Token, "regular expressions are not supported by the dialect")
+		}
+		return cond, nil
+	default:
+		op, found := comparisons[c.Operator.Type]
+		if !found {
+			return "", lexer.ErrorAt(c.Operator.Token, "unsupported operator %s", c.Operator.Type)
+		}
+		return column + " " + op + " " + t.bind(value), nil
+	}
+}
@@ -0,0 +1,110 @@
+package sql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type testcase struct {
+	input   string
+	dialect Dialect
+	clause  string
+	args    []any
+}
+
+var columns = map[string]string{
+	"callsign": "callsign",
+	"alt":      "altitude",
+	"arrival":  "fp.arrival",
+
+	"flight_plan.departure": "fp.departure",
+}
+
+var validCases = []testcase{
+	{
+		`callsign = "AFL123"`,
+		Postgres,
+		`"callsign" = $1`,
+		[]any{"AFL123"},
+	},
+	{
+		`alt > 1000 and callsign =~ "^AFL" or arrival != 'UUEE'`,
+		Postgres,
+		`"altitude" > $1 AND ("callsign" ~ $2 OR "fp"."arrival" <> $3)`,
+		[]any{1000.0, "^AFL", "UUEE"},
+	},
+	{
+		`(alt > 1000 or alt < 10) and callsign !~ "^AFL"`,
+		SQLite,
+		`("altitude" > ? OR "altitude" < ?) AND "callsign" NOT REGEXP ?`,
+		[]any{1000.0, 10.0, "^AFL"},
+	},
+	{
+		`alt >= 1 and alt <= 2 and (callsign = "X")`,
+		MySQL,
+		"`altitude` >= ? AND `altitude` <= ? AND `callsign` = ?",
+		[]any{1.0, 2.0, "X"},
+	},
+	{
+		`callsign = "'; DROP TABLE pilots; --"`,
+		SQLite,
+		`"callsign" = ?`,
+		[]any{"'; DROP TABLE pilots; --"},
+	},
+	{
+		`flight_plan.departure = "EGLL"`,
+		Postgres,
+		`"fp"."departure" = $1`,
+		[]any{"EGLL"},
+	},
+}
+
+func parse(t *testing.T, src string) *parser.Expression[any] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[any](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+func TestWhere(t *testing.T) {
+	for i, tc := range validCases {
+		clause, args, err := Where(parse(t, tc.input), Options{Dialect: tc.dialect, Columns: columns})
+		if err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if clause != tc.clause {
+			t.Errorf("invalid clause in case %d, got %s, expected %s", i+1, clause, tc.clause)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("invalid args in case %d, got %v, expected %v", i+1, args, tc.args)
+		}
+	}
+}
+
+func TestWhereErrors(t *testing.T) {
+	cases := map[string]string{
+		`callsign = "A" and unknown = 1`: "identifier unknown has no column mapping at line 1 pos 20",
+		`callsign =~ 5`:                  "regular expression must be a string at line 1 pos 13",
+	}
+
+	for input, exp := range cases {
+		_, _, err := Where(parse(t, input), Options{Columns: columns})
+		if err == nil {
+			t.Errorf("%s should fail", input)
+			continue
+		}
+		if err.Error() != exp {
+			t.Errorf("should throw %v, but throws %v", exp, err)
+		}
+	}
+}
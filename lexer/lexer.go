@@ -58,6 +58,18 @@ func (l *lexer) advance() {
 func (l *lexer) readNumber() error {
 	pos := l.pos
 	line := l.line
+
+	// a leading minus makes a negative number
+	r, _, err := l.sc.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r == '-' {
+		l.eat(r)
+	} else {
+		l.rewind()
+	}
+
 	dotFound := false
 	for {
 		r, _, err := l.sc.ReadRune()
@@ -82,6 +94,11 @@ func (l *lexer) readNumber() error {
 			break
 		}
 	}
+	// the minus must be followed by a digit
+	if l.literal == "-" || strings.HasPrefix(l.literal, "-.") {
+		l.push(Illegal, line, pos)
+		return nil
+	}
 	l.push(Number, line, pos)
 	return nil
 }
@@ -120,13 +137,19 @@ func (l *lexer) readIdentifier() error {
 			return err
 		}
 
-		if !exprIdent.MatchString(string(r)) {
+		// dots separate the parts of a path into nested fields
+		if r != '.' && !exprIdent.MatchString(string(r)) {
 			l.rewind()
 			break
 		}
 		l.eat(r)
 	}
 
+	if strings.HasSuffix(l.literal, ".") || strings.Contains(l.literal, "..") {
+		l.push(Illegal, line, pos)
+		return nil
+	}
+
 	operator := strings.ToLower(l.literal)
 	if operator == "or" {
 		l.push(Or, line, pos)
@@ -358,6 +381,53 @@ func (l *lexer) readStringLiteral() error {
 	return nil
 }
 
+// readSigilName reads an identifier prefixed with a sigil, i.e.
+// a $placeholder or a @reference
+func (l *lexer) readSigilName(t TokenType) error {
+	line := l.line
+	pos := l.pos
+
+	// read the sigil
+	r, _, err := l.sc.ReadRune()
+	if err != nil {
+		return err
+	}
+	l.eat(r)
+
+	r, _, err = l.sc.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			l.push(Illegal, line, pos)
+			return nil
+		}
+		return err
+	}
+	l.rewind()
+
+	if !exprIdentStart.MatchString(string(r)) {
+		l.push(Illegal, line, pos)
+		return nil
+	}
+
+	for {
+		r, _, err := l.sc.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		if !exprIdent.MatchString(string(r)) {
+			l.rewind()
+			break
+		}
+		l.eat(r)
+	}
+	l.push(t, line, pos)
+	return nil
+}
+
 func (l *lexer) readAll() error {
 	var r rune
 	var err error
@@ -373,7 +443,7 @@ func (l *lexer) readAll() error {
 		}
 		l.rewind()
 
-		if r >= '0' && r <= '9' {
+		if r >= '0' && r <= '9' || r == '-' {
 			if err = l.readNumber(); err != nil {
 				return err
 			}
@@ -405,6 +475,14 @@ func (l *lexer) readAll() error {
 			if err = l.readStringLiteral(); err != nil {
 				return err
 			}
+		} else if r == '$' || r == ':' {
+			if err = l.readSigilName(Placeholder); err != nil {
+				return err
+			}
+		} else if r == '@' {
+			if err = l.readSigilName(Reference); err != nil {
+				return err
+			}
 		} else if r == '&' {
 			if err = l.readAnd(); err != nil {
 				return err
@@ -452,3 +530,15 @@ func Tokenize(data string, skipWhitespace bool) (*TokenFlow, error) {
 
 	return newTokenFlow(l.tokens, skipWhitespace), nil
 }
+
+// Is reports whether the literal reads as exactly one token of the type,
+// i.e. Is(Identifier, "flight_plan.arrival") is true while Is(Identifier,
+// "a b") and Is(Identifier, "and") are false
+func Is(t TokenType, literal string) bool {
+	tf, err := Tokenize(literal, false)
+	if err != nil {
+		return false
+	}
+	tokens := tf.Tokens()
+	return len(tokens) == 2 && tokens[0].Type == t && tokens[0].Literal == literal
+}
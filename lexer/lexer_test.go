@@ -1,6 +1,9 @@
 package lexer
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 type testcase struct {
 	input  string
@@ -31,6 +34,71 @@ var (
 				{EOF, "", 1, 21},
 			},
 		},
+		{
+			"d < $radius or arrival = :airport_1",
+			[]Token{
+				{Identifier, "d", 1, 1},
+				{Less, "<", 1, 3},
+				{Placeholder, "$radius", 1, 5},
+				{Or, "or", 1, 13},
+				{Identifier, "arrival", 1, 16},
+				{Equals, "=", 1, 24},
+				{Placeholder, ":airport_1", 1, 26},
+				{EOF, "", 1, 36},
+			},
+		},
+		{
+			"@uk_arrivals and (@heavy)",
+			[]Token{
+				{Reference, "@uk_arrivals", 1, 1},
+				{And, "and", 1, 14},
+				{LBrace, "(", 1, 18},
+				{Reference, "@heavy", 1, 19},
+				{RBrace, ")", 1, 25},
+				{EOF, "", 1, 26},
+			},
+		},
+		{
+			`flight_plan.arrival =~ "^EG" and a. = 1`,
+			[]Token{
+				{Identifier, "flight_plan.arrival", 1, 1},
+				{Matches, "=~", 1, 21},
+				{String, `"^EG"`, 1, 24},
+				{And, "and", 1, 30},
+				{Illegal, "a.", 1, 34},
+				{Equals, "=", 1, 37},
+				{Number, "1", 1, 39},
+				{EOF, "", 1, 40},
+			},
+		},
+		{
+			"lon > -5.5 and lat<-1 or x = - 1",
+			[]Token{
+				{Identifier, "lon", 1, 1},
+				{Greater, ">", 1, 5},
+				{Number, "-5.5", 1, 7},
+				{And, "and", 1, 12},
+				{Identifier, "lat", 1, 16},
+				{Less, "<", 1, 19},
+				{Number, "-1", 1, 20},
+				{Or, "or", 1, 23},
+				{Identifier, "x", 1, 26},
+				{Equals, "=", 1, 28},
+				{Illegal, "-", 1, 30},
+				{Number, "1", 1, 32},
+				{EOF, "", 1, 33},
+			},
+		},
+		{
+			"a = $ 1",
+			[]Token{
+				{Identifier, "a", 1, 1},
+				{Equals, "=", 1, 3},
+				{Illegal, "$", 1, 5},
+				{Number, "1", 1, 7},
+				{EOF, "", 1, 8},
+			},
+		},
 	}
 )
 
@@ -41,6 +109,9 @@ func TestLexerValidCases(t *testing.T) {
 			t.Errorf("error testing case %d: %v", i+1, err)
 			return
 		}
+		if n := len(tf.Tokens()); n != len(tc.output) {
+			t.Errorf("error in test %d: got %d tokens, expected %d", i+1, n, len(tc.output))
+		}
 
 		for i := 0; i < len(tc.output); i++ {
 			expected := tc.output[i]
@@ -65,3 +136,75 @@ func TestLexerValidCases(t *testing.T) {
 		}
 	}
 }
+
+func TestIs(t *testing.T) {
+	cases := []struct {
+		t       TokenType
+		literal string
+		result  bool
+	}{
+		{Identifier, "callsign", true},
+		{Identifier, "flight_plan.arrival", true},
+		{Identifier, "legs.0.fix", true},
+		{Identifier, "a..b", false},
+		{Identifier, "a.", false},
+		{Identifier, "a b", false},
+		{Identifier, " a", false},
+		{Identifier, "and", false},
+		{Identifier, "1a", false},
+		{Identifier, "", false},
+		{Placeholder, "$airport", true},
+		{Placeholder, "$a.b", false},
+		{Reference, "@europe", true},
+		{Number, "-12.5", true},
+		{Number, "-.5", false},
+	}
+	for _, tc := range cases {
+		if result := Is(tc.t, tc.literal); result != tc.result {
+			t.Errorf("Is(%s, %q) got %v, expected %v", tc.t, tc.literal, result, tc.result)
+		}
+	}
+}
+
+func TestErrorAt(t *testing.T) {
+	base := errors.New("base")
+	cases := []struct {
+		token *Token
+		err   string
+	}{
+		{&Token{Identifier, "a", 2, 5}, "invalid a: base at line 2 pos 5"},
+		{&Token{Identifier, "a", 0, 0}, "invalid a: base"},
+		{nil, "invalid a: base"},
+	}
+	for _, tc := range cases {
+		err := ErrorAt(tc.token, "invalid %s: %w", "a", base)
+		if err.Error() != tc.err {
+			t.Errorf("got %s, expected %s", err, tc.err)
+		}
+		if !errors.Is(err, base) {
+			t.Errorf("%s must wrap the base error", err)
+		}
+	}
+}
+
+func TestTokenTypeText(t *testing.T) {
+	// the values are part of the API, new types go after And
+	if NotEquals != 6 || And != 17 || Placeholder != 18 || Reference != 19 {
+		t.Errorf("token type values changed")
+	}
+
+	for tt := Illegal; tt <= Reference; tt++ {
+		text, err := tt.MarshalText()
+		if err != nil {
+			t.Errorf("unexpected error marshaling %d: %v", tt, err)
+			continue
+		}
+		var decoded TokenType
+		if err := decoded.UnmarshalText(text); err != nil || decoded != tt {
+			t.Errorf("%s decoded as %s, %v", text, decoded, err)
+		}
+	}
+	if _, err := (Reference + 1).MarshalText(); err == nil {
+		t.Errorf("invalid token type must fail")
+	}
+}
@@ -14,7 +14,12 @@ const (
 	EOF
 	WhiteSpace
 
+	// Identifier is a field name or a dotted path into nested fields,
+	// i.e. flight_plan.arrival or legs.0.fix, the parts after the first
+	// may start with a digit
 	Identifier
+	// Number is a decimal number, a leading minus makes it negative,
+	// i.e. 12.5 or -3
 	Number
 	String
 
@@ -32,14 +37,21 @@ const (
 
 	Or
 	And
+
+	// new token types are appended to keep the values stable
+
+	// Placeholder is a named parameter in value position, $name or :name
+	Placeholder
+	// Reference names a filter macro, @name
+	Reference
 )
 
 type (
 	Token struct {
-		Type     TokenType
-		Literal  string
-		Line     int
-		Position int
+		Type     TokenType `json:"type"`
+		Literal  string    `json:"literal"`
+		Line     int       `json:"line"`
+		Position int       `json:"position"`
 	}
 
 	TokenFlow struct {
@@ -59,6 +71,35 @@ func (t Token) String() string {
 	)
 }
 
+// ErrorAt formats an error at the position of the token, nil tokens and
+// the synthesized ones, which have zero line, add no position. The
+// arguments may wrap errors with %w.
+func ErrorAt(t *Token, format string, args ...any) error {
+	if t == nil || t.Line == 0 {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Errorf(format+" at line %d pos %d", append(args, t.Line, t.Position)...)
+}
+
+// MarshalText encodes the token type by its name
+func (tt TokenType) MarshalText() ([]byte, error) {
+	if tt < Illegal || tt > Reference {
+		return nil, fmt.Errorf("invalid token type %d", int(tt))
+	}
+	return []byte(tt.String()), nil
+}
+
+// UnmarshalText decodes a token type name as produced by MarshalText
+func (tt *TokenType) UnmarshalText(text []byte) error {
+	for t := Illegal; t <= Reference; t++ {
+		if t.String() == string(text) {
+			*tt = t
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token type %s", text)
+}
+
 func (t Token) ne(o Token) bool {
 	return t.Type != o.Type ||
 		t.Literal != o.Literal ||
@@ -77,6 +118,13 @@ func newTokenFlow(tokens []Token, skipWhitespace bool) *TokenFlow {
 	return &tf
 }
 
+// Tokens returns a copy of all the tokens of the flow
+func (tf *TokenFlow) Tokens() []Token {
+	tokens := make([]Token, len(tf.tokens))
+	copy(tokens, tf.tokens)
+	return tokens
+}
+
 // Reset resets the current index to zero
 func (tf *TokenFlow) Reset() {
 	tf.idx = 0
@@ -26,15 +26,18 @@ func _() {
 	_ = x[RBrace-15]
 	_ = x[Or-16]
 	_ = x[And-17]
+	_ = x[Placeholder-18]
+	_ = x[Reference-19]
 }
 
-const _TokenType_name = "IllegalEOFWhiteSpaceIdentifierNumberStringNotEqualsEqualsMatchesNotMatchesLessGreaterLessOrEqualGreaterOrEqualLBraceRBraceOrAnd"
+const _TokenType_name = "IllegalEOFWhiteSpaceIdentifierNumberStringNotEqualsEqualsMatchesNotMatchesLessGreaterLessOrEqualGreaterOrEqualLBraceRBraceOrAndPlaceholderReference"
 
-var _TokenType_index = [...]uint8{0, 7, 10, 20, 30, 36, 42, 51, 57, 64, 74, 78, 85, 96, 110, 116, 122, 124, 127}
+var _TokenType_index = [...]uint8{0, 7, 10, 20, 30, 36, 42, 51, 57, 64, 74, 78, 85, 96, 110, 116, 122, 124, 127, 138, 147}
 
 func (i TokenType) String() string {
-	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TokenType_index)-1 {
 		return "TokenType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _TokenType_name[_TokenType_index[i]:_TokenType_index[i+1]]
+	return _TokenType_name[_TokenType_index[idx]:_TokenType_index[idx+1]]
 }
@@ -0,0 +1,53 @@
+package logic
+
+import (
+	"github.com/vatsimnerd/lee/parser"
+)
+
+// Implies reports whether every model matching a also matches b. The check
+// is sound but incomplete: true is always right, false may also mean the
+// implication couldn't be proven, i.e. it depends on patterns, placeholders
+// or conditions on different fields, or the filters are too large to
+// normalize.
+//
+// Every branch of a's DNF is checked against every clause of b's CNF, the
+// branch implies the clause when the branch conditions together with the
+// negated clause conditions conflict like in Analyze.
+func Implies[T any](a, b *parser.Expression[T]) bool {
+	return NodeImplies(FromExpression(a), FromExpression(b))
+}
+
+// NodeImplies is Implies for trees which may contain constants
+func NodeImplies[T any](a, b *Node[T]) bool {
+	dnf, err := NodeToDNF(a, DefaultMaxTerms)
+	if err != nil {
+		return false
+	}
+	cnf, err := NodeToCNF(b, DefaultMaxTerms)
+	if err != nil {
+		return false
+	}
+
+	for _, conj := range dnf {
+		for _, disj := range cnf {
+			if !conjunctionImplies(conj, disj) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func conjunctionImplies[T any](conj Conjunction[T], disj Disjunction[T]) bool {
+	c := newConstraints[T]()
+	if c.addAll(conj) != nil {
+		// a branch which never matches implies anything
+		return true
+	}
+	for _, cond := range disj {
+		if c.addNegated(cond) != nil {
+			return true
+		}
+	}
+	return false
+}
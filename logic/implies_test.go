@@ -0,0 +1,119 @@
+package logic
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type impliesCase struct {
+	a, b    string
+	implies bool
+}
+
+var impliesCases = []impliesCase{
+	{`x < 3`, `x < 5`, true},
+	{`x < 5`, `x < 3`, false},
+	{`x < 3`, `x <= 3`, true},
+	{`x >= 3`, `x > 3`, false},
+	{`x = 1`, `x >= 1`, true},
+	{`x = 1`, `x != 2`, true},
+	{`x < 3`, `x != 5`, true},
+	{`x != 1`, `x != 1`, true},
+	{`x != 1`, `x != 2`, false},
+	{`x != 1`, `x < 5`, false},
+	{`x = 1`, `x != "a"`, true},
+	{`x = "a"`, `x < "b"`, true},
+	{`x = "EGLL"`, `x =~ "^EG"`, true},
+	{`x = "LFPG"`, `x =~ "^EG"`, false},
+	{`x =~ "^EG"`, `x =~ "^EG"`, true},
+	{`x =~ "^EG"`, `x =~ "^E"`, false},
+	{`a = 1 and b = 2`, `a = 1`, true},
+	{`a = 1`, `a = 1 and b = 2`, false},
+	{`a = 1`, `a = 1 or b = 2`, true},
+	{`(a = 1 or a = 2) and b > 10`, `a <= 2 and b > 5`, true},
+	{`(a = 1 or a = 3) and b > 10`, `a <= 2 and b > 5`, false},
+	{`x > 1 and x < 1`, `y = 1`, true},
+	{`x > 1`, `x > 0 or x < 0`, true},
+	{`x = $a`, `x = $a`, false},
+}
+
+func TestImplies(t *testing.T) {
+	for i, tc := range impliesCases {
+		if result := Implies(parse(t, tc.a), parse(t, tc.b)); result != tc.implies {
+			t.Errorf("invalid result in case %d, %s implies %s got %v, expected %v", i+1, tc.a, tc.b, result, tc.implies)
+		}
+	}
+}
+
+// randomFilter makes filters over two fields with values close to each
+// other, so conflicts and implications are frequent
+func randomFilter(r *rand.Rand, depth int) string {
+	ops := []string{"=", "!=", "<", "<=", ">", ">="}
+	values := []string{"1", "2", "3", `"a"`, `"b"`}
+
+	var sb strings.Builder
+	operands := r.Intn(3) + 1
+	for i := 0; i < operands; i++ {
+		if i > 0 {
+			sb.WriteString([]string{" and ", " or "}[r.Intn(2)])
+		}
+		if depth > 0 && r.Intn(3) == 0 {
+			sb.WriteString("(" + randomFilter(r, depth-1) + ")")
+			continue
+		}
+		sb.WriteString([]string{"x", "y"}[r.Intn(2)] + " " + ops[r.Intn(len(ops))] + " " + values[r.Intn(len(values))])
+	}
+	return sb.String()
+}
+
+func compileModel(t *testing.T, src string) *parser.Program[map[string]any] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[map[string]any](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	p, err := expr.Compile(match.Compiler(map[string]match.Accessor[map[string]any]{
+		"x": func(m map[string]any) any { return m["x"] },
+		"y": func(m map[string]any) any { return m["y"] },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAnalysisSound(t *testing.T) {
+	domain := []any{nil, 0.0, 1.0, 1.5, 2.0, 2.5, 3.0, 4.0, "", "a", "ab", "b", "c"}
+	var models []map[string]any
+	for _, x := range domain {
+		for _, y := range domain {
+			models = append(models, map[string]any{"x": x, "y": y})
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		srcA, srcB := randomFilter(r, 2), randomFilter(r, 2)
+		a, b := compileModel(t, srcA), compileModel(t, srcB)
+
+		analysis := Analyze(a.Expression())
+		implies := Implies(a.Expression(), b.Expression())
+		for _, m := range models {
+			if analysis.Verdict == Unsatisfiable && a.Evaluate(m) {
+				t.Fatalf("%s is unsatisfiable but matches %v", srcA, m)
+			}
+			if implies && a.Evaluate(m) && !b.Evaluate(m) {
+				t.Fatalf("%s implies %s but %v only matches the former", srcA, srcB, m)
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=Kind"; DO NOT EDIT.
+
+package logic
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Cond-0]
+	_ = x[And-1]
+	_ = x[Or-2]
+	_ = x[True-3]
+	_ = x[False-4]
+}
+
+const _Kind_name = "CondAndOrTrueFalse"
+
+var _Kind_index = [...]uint8{0, 4, 7, 9, 13, 18}
+
+func (i Kind) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Kind_index)-1 {
+		return "Kind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Kind_name[_Kind_index[idx]:_Kind_index[idx+1]]
+}
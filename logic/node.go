@@ -0,0 +1,182 @@
+// Package logic works with expressions as n-ary trees of and/or nodes
+// which are easier to rewrite and analyze than the right-leaning
+// parser.Expression chains.
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+//go:generate stringer -type=Kind
+
+type (
+	Kind int
+
+	// Node is a condition, a constant or an and/or of any number of
+	// children. The parser has no boolean literals, constants only appear
+	// as a result of rewrites.
+	Node[T any] struct {
+		Kind      Kind
+		Condition *parser.Condition[T]
+		Children  []*Node[T]
+	}
+)
+
+const (
+	Cond Kind = iota
+	And
+	Or
+	True
+	False
+)
+
+// NewCondition makes a condition leaf
+func NewCondition[T any](c *parser.Condition[T]) *Node[T] {
+	return &Node[T]{Kind: Cond, Condition: c}
+}
+
+// NewConst makes a constant leaf
+func NewConst[T any](value bool) *Node[T] {
+	if value {
+		return &Node[T]{Kind: True}
+	}
+	return &Node[T]{Kind: False}
+}
+
+// FromExpression converts the expression into a tree where every chain of
+// the same combine operator becomes a single node, including the groupings
+// of the same operator on either side, so "(a and b) and c" is one node
+func FromExpression[T any](e *parser.Expression[T]) *Node[T] {
+	var left *Node[T]
+	if e.Left.Condition != nil {
+		left = NewCondition(e.Left.Condition)
+	} else {
+		left = FromExpression(e.Left.Grouping.Expression)
+	}
+
+	if e.Right == nil {
+		return left
+	}
+
+	kind := And
+	if e.Operator.Type == parser.Or {
+		kind = Or
+	}
+
+	n := &Node[T]{Kind: kind}
+	for _, child := range []*Node[T]{left, FromExpression(e.Right)} {
+		if child.Kind == kind {
+			n.Children = append(n.Children, child.Children...)
+		} else {
+			n.Children = append(n.Children, child)
+		}
+	}
+	return n
+}
+
+// ToExpression converts the tree back to an expression, children of
+// a different kind become groupings. Constants have no representation in
+// the filter language and can't be converted.
+func (n *Node[T]) ToExpression() (*parser.Expression[T], error) {
+	switch n.Kind {
+	case Cond:
+		return &parser.Expression[T]{Left: &parser.LeftExpression[T]{Condition: n.Condition}}, nil
+	case True, False:
+		return nil, fmt.Errorf("constant %s can't be converted to an expression", n)
+	}
+
+	if len(n.Children) == 0 {
+		return nil, fmt.Errorf("%s node has no children", n.Kind)
+	}
+
+	op := &parser.CombineOperator{Type: parser.And, Token: &lexer.Token{Type: lexer.And, Literal: "and"}}
+	if n.Kind == Or {
+		op = &parser.CombineOperator{Type: parser.Or, Token: &lexer.Token{Type: lexer.Or, Literal: "or"}}
+	}
+
+	var expr *parser.Expression[T]
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		child := n.Children[i]
+		left := &parser.LeftExpression[T]{}
+		if child.Kind == Cond {
+			left.Condition = child.Condition
+		} else {
+			inner, err := child.ToExpression()
+			if err != nil {
+				return nil, err
+			}
+			left.Grouping = &parser.Grouping[T]{Expression: inner}
+		}
+
+		if expr == nil {
+			expr = &parser.Expression[T]{Left: left}
+		} else {
+			expr = &parser.Expression[T]{Left: left, Operator: op, Right: expr}
+		}
+	}
+	return expr, nil
+}
+
+// IsConst reports whether the node is a constant
+func (n *Node[T]) IsConst() bool {
+	return n.Kind == True || n.Kind == False
+}
+
+// Key is a canonical representation of the node, nodes with equal keys
+// are logically equivalent. Children are sorted as and/or are commutative.
+func (n *Node[T]) Key() string {
+	switch n.Kind {
+	case Cond:
+		return n.Condition.Format()
+	case True:
+		return "true"
+	case False:
+		return "false"
+	}
+
+	keys := make([]string, len(n.Children))
+	for i, child := range n.Children {
+		keys[i] = child.Key()
+	}
+	sort.Strings(keys)
+	return strings.ToLower(n.Kind.String()) + "(" + strings.Join(keys, ", ") + ")"
+}
+
+// String prints the node as filter source with every nested node
+// parenthesized
+func (n *Node[T]) String() string {
+	switch n.Kind {
+	case Cond:
+		return n.Condition.Format()
+	case True:
+		return "true"
+	case False:
+		return "false"
+	}
+
+	parts := make([]string, len(n.Children))
+	for i, child := range n.Children {
+		parts[i] = child.String()
+		if child.Kind == And || child.Kind == Or {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " "+strings.ToLower(n.Kind.String())+" ")
+}
+
+// Conditions returns all the condition leaves in order
+func (n *Node[T]) Conditions() []*parser.Condition[T] {
+	if n.Kind == Cond {
+		return []*parser.Condition[T]{n.Condition}
+	}
+	var conds []*parser.Condition[T]
+	for _, child := range n.Children {
+		conds = append(conds, child.Conditions()...)
+	}
+	return conds
+}
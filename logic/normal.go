@@ -0,0 +1,303 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Conjunction is an and of conditions, an empty one is true
+	Conjunction[T any] []*parser.Condition[T]
+
+	// Disjunction is an or of conditions, an empty one is false
+	Disjunction[T any] []*parser.Condition[T]
+
+	// DNF is an or of conjunctions, an empty one is false
+	DNF[T any] []Conjunction[T]
+
+	// CNF is an and of disjunctions, an empty one is true
+	CNF[T any] []Disjunction[T]
+
+	// clause is a conjunction or a disjunction depending on the form
+	clause[T any] []*parser.Condition[T]
+)
+
+// DefaultMaxTerms limits the size of normal forms when no other limit is
+// given
+const DefaultMaxTerms = 1024
+
+// ToDNF rewrites the expression as an or of ands by distributing ands over
+// ors. The number of conjunctions may grow exponentially with the size of
+// the expression, conversions producing more than maxTerms of them fail
+// before doing any work. Zero maxTerms means DefaultMaxTerms.
+func ToDNF[T any](e *parser.Expression[T], maxTerms int) (DNF[T], error) {
+	return NodeToDNF(FromExpression(e), maxTerms)
+}
+
+// ToCNF rewrites the expression as an and of ors, see ToDNF
+func ToCNF[T any](e *parser.Expression[T], maxTerms int) (CNF[T], error) {
+	return NodeToCNF(FromExpression(e), maxTerms)
+}
+
+// NodeToDNF is ToDNF for trees which may contain constants
+func NodeToDNF[T any](n *Node[T], maxTerms int) (DNF[T], error) {
+	clauses, err := normalize(n, Or, maxTerms)
+	if err != nil {
+		return nil, err
+	}
+	dnf := make(DNF[T], len(clauses))
+	for i, c := range clauses {
+		dnf[i] = Conjunction[T](c)
+	}
+	return dnf, nil
+}
+
+// NodeToCNF is ToCNF for trees which may contain constants
+func NodeToCNF[T any](n *Node[T], maxTerms int) (CNF[T], error) {
+	clauses, err := normalize(n, And, maxTerms)
+	if err != nil {
+		return nil, err
+	}
+	cnf := make(CNF[T], len(clauses))
+	for i, c := range clauses {
+		cnf[i] = Disjunction[T](c)
+	}
+	return cnf, nil
+}
+
+// normalize returns the clauses of the normal form whose top level node
+// is of the outer kind
+func normalize[T any](n *Node[T], outer Kind, maxTerms int) ([]clause[T], error) {
+	if maxTerms <= 0 {
+		maxTerms = DefaultMaxTerms
+	}
+	name := "DNF"
+	if outer == And {
+		name = "CNF"
+	}
+	if _, ok := countClauses(n, outer, maxTerms); !ok {
+		return nil, fmt.Errorf("%s of the expression would have more than %d terms", name, maxTerms)
+	}
+	return simplify(clauses(n, outer)), nil
+}
+
+// identity returns the constant which doesn't change an inner clause, true
+// for conjunctions and false for disjunctions
+func identity(outer Kind) Kind {
+	if outer == Or {
+		return True
+	}
+	return False
+}
+
+// countClauses computes the number of clauses before simplification, ok
+// is false if there are more than limit of them
+func countClauses[T any](n *Node[T], outer Kind, limit int) (count int, ok bool) {
+	switch n.Kind {
+	case Cond:
+		return 1, true
+	case True, False:
+		if n.Kind == identity(outer) {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if n.Kind == outer {
+		total := 0
+		for _, child := range n.Children {
+			count, ok := countClauses(child, outer, limit)
+			if !ok || count > limit-total {
+				return 0, false
+			}
+			total += count
+		}
+		return total, true
+	}
+
+	// a child without clauses empties the whole product, so every child is
+	// counted before giving up on the limit
+	counts := make([]int, 0, len(n.Children))
+	over := false
+	for _, child := range n.Children {
+		count, ok := countClauses(child, outer, limit)
+		if ok && count == 0 {
+			return 0, true
+		}
+		over = over || !ok
+		counts = append(counts, count)
+	}
+	if over {
+		return 0, false
+	}
+
+	total := 1
+	for _, count := range counts {
+		if total > limit/count {
+			return 0, false
+		}
+		total *= count
+	}
+	return total, true
+}
+
+func clauses[T any](n *Node[T], outer Kind) []clause[T] {
+	switch n.Kind {
+	case Cond:
+		return []clause[T]{{n.Condition}}
+	case True, False:
+		if n.Kind == identity(outer) {
+			return []clause[T]{{}}
+		}
+		return nil
+	}
+
+	if n.Kind == outer {
+		var result []clause[T]
+		for _, child := range n.Children {
+			result = append(result, clauses(child, outer)...)
+		}
+		return result
+	}
+
+	// distribute: every combination of one clause from each child
+	result := []clause[T]{{}}
+	for _, child := range n.Children {
+		var product []clause[T]
+		childClauses := clauses(child, outer)
+		for _, prefix := range result {
+			for _, c := range childClauses {
+				merged := make(clause[T], 0, len(prefix)+len(c))
+				merged = append(merged, prefix...)
+				merged = append(merged, c...)
+				product = append(product, merged)
+			}
+		}
+		result = product
+	}
+	return result
+}
+
+// simplify removes repeated conditions from clauses, repeated clauses and
+// clauses absorbed by a smaller one, i.e. a or (a and b) is a
+func simplify[T any](clauses []clause[T]) []clause[T] {
+	type keyed struct {
+		clause clause[T]
+		keys   map[string]bool
+	}
+
+	var unique []keyed
+	seen := make(map[string]bool)
+	for _, c := range clauses {
+		keys := make(map[string]bool)
+		var deduped clause[T]
+		for _, cond := range c {
+			key := cond.Format()
+			if !keys[key] {
+				keys[key] = true
+				deduped = append(deduped, cond)
+			}
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		clauseKey := strings.Join(sorted, "\x00")
+		if seen[clauseKey] {
+			continue
+		}
+		seen[clauseKey] = true
+		unique = append(unique, keyed{deduped, keys})
+	}
+
+	var result []clause[T]
+	for i, c := range unique {
+		absorbed := false
+		for j, other := range unique {
+			if i != j && len(other.keys) < len(c.keys) && subset(other.keys, c.keys) {
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			result = append(result, c.clause)
+		}
+	}
+	return result
+}
+
+// Node converts the conjunction to an and node, or a single condition
+func (c Conjunction[T]) Node() *Node[T] {
+	return clauseNode(c, And)
+}
+
+// Node converts the disjunction to an or node, or a single condition
+func (d Disjunction[T]) Node() *Node[T] {
+	return clauseNode(d, Or)
+}
+
+func clauseNode[T any](conds []*parser.Condition[T], kind Kind) *Node[T] {
+	if len(conds) == 0 {
+		return NewConst[T](kind == And)
+	}
+	if len(conds) == 1 {
+		return NewCondition(conds[0])
+	}
+	n := &Node[T]{Kind: kind}
+	for _, cond := range conds {
+		n.Children = append(n.Children, NewCondition(cond))
+	}
+	return n
+}
+
+// Node converts the normal form back to a tree
+func (d DNF[T]) Node() *Node[T] {
+	nodes := make([]*Node[T], len(d))
+	for i, c := range d {
+		nodes[i] = c.Node()
+	}
+	return formNode(nodes, Or)
+}
+
+// Node converts the normal form back to a tree
+func (c CNF[T]) Node() *Node[T] {
+	nodes := make([]*Node[T], len(c))
+	for i, d := range c {
+		nodes[i] = d.Node()
+	}
+	return formNode(nodes, And)
+}
+
+func formNode[T any](nodes []*Node[T], kind Kind) *Node[T] {
+	switch len(nodes) {
+	case 0:
+		return NewConst[T](kind == And)
+	case 1:
+		return nodes[0]
+	}
+	return &Node[T]{Kind: kind, Children: nodes}
+}
+
+// Expression converts the normal form to an expression, it fails for the
+// constant forms which the filter language can't express
+func (d DNF[T]) Expression() (*parser.Expression[T], error) {
+	return d.Node().ToExpression()
+}
+
+// Expression converts the normal form to an expression, see DNF.Expression
+func (c CNF[T]) Expression() (*parser.Expression[T], error) {
+	return c.Node().ToExpression()
+}
+
+func (d DNF[T]) String() string {
+	return d.Node().String()
+}
+
+func (c CNF[T]) String() string {
+	return c.Node().String()
+}
@@ -0,0 +1,172 @@
+package logic
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type normalCase struct {
+	input string
+	dnf   string
+	cnf   string
+}
+
+var normalCases = []normalCase{
+	{`c0 = 1`, `c0 = 1`, `c0 = 1`},
+	{
+		`(c0 = 1 or c1 = 1) and c2 = 1`,
+		`(c0 = 1 and c2 = 1) or (c1 = 1 and c2 = 1)`,
+		`(c0 = 1 or c1 = 1) and c2 = 1`,
+	},
+	{
+		`c0 = 1 and c1 = 1 or c2 = 1`,
+		`(c0 = 1 and c1 = 1) or (c0 = 1 and c2 = 1)`,
+		`c0 = 1 and (c1 = 1 or c2 = 1)`,
+	},
+	{
+		`(c0 = 1 or c1 = 1) and (c0 = 1 or c2 = 1)`,
+		`c0 = 1 or (c1 = 1 and c2 = 1)`,
+		`(c0 = 1 or c1 = 1) and (c0 = 1 or c2 = 1)`,
+	},
+	{
+		`(c0 = 1 and c0 = 1) or (c0 = 1 and c1 = 1)`,
+		`c0 = 1`,
+		`c0 = 1`,
+	},
+}
+
+func TestNormalForms(t *testing.T) {
+	for i, tc := range normalCases {
+		expr := parse(t, tc.input)
+		dnf, err := ToDNF(expr, 0)
+		if err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if dnf.String() != tc.dnf {
+			t.Errorf("invalid DNF in case %d, got %s, expected %s", i+1, dnf, tc.dnf)
+		}
+
+		cnf, err := ToCNF(expr, 0)
+		if err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if cnf.String() != tc.cnf {
+			t.Errorf("invalid CNF in case %d, got %s, expected %s", i+1, cnf, tc.cnf)
+		}
+	}
+}
+
+func TestNormalFormLimit(t *testing.T) {
+	// (a0 or b0) and (a1 or b1) and ... has 2^n conjunctions
+	parts := make([]string, 11)
+	for i := range parts {
+		parts[i] = "(c0 = " + strconv.Itoa(i) + " or c1 = 1)"
+	}
+	expr := parse(t, strings.Join(parts, " and "))
+
+	_, err := ToDNF(expr, 0)
+	if err == nil || err.Error() != "DNF of the expression would have more than 1024 terms" {
+		t.Errorf("got %v, expected the limit error", err)
+	}
+	if _, err := ToDNF(expr, 4096); err != nil {
+		t.Errorf("unexpected error with a higher limit: %v", err)
+	}
+	if cnf, err := ToCNF(expr, 0); err != nil || len(cnf) != 11 {
+		t.Errorf("CNF must have 11 clauses, got %d, %v", len(cnf), err)
+	}
+	if _, err := ToDNF(expr, math.MaxInt); err != nil {
+		t.Errorf("unexpected error with the largest limit: %v", err)
+	}
+
+	// a false operand empties the product however large the rest is
+	n := &Node[uint]{Kind: And, Children: []*Node[uint]{FromExpression(expr), NewConst[uint](false)}}
+	if dnf, err := NodeToDNF(n, 0); err != nil || dnf.String() != "false" {
+		t.Errorf("got %s, %v, expected false", dnf, err)
+	}
+}
+
+func TestNormalFormConstants(t *testing.T) {
+	c := NewCondition(parse(t, `c0 = 1`).Left.Condition)
+
+	cases := []struct {
+		node *Node[uint]
+		dnf  string
+		cnf  string
+	}{
+		{NewConst[uint](true), "true", "true"},
+		{NewConst[uint](false), "false", "false"},
+		{&Node[uint]{Kind: And, Children: []*Node[uint]{c, NewConst[uint](false)}}, "false", "false"},
+		{&Node[uint]{Kind: Or, Children: []*Node[uint]{c, NewConst[uint](true)}}, "true", "true"},
+		{&Node[uint]{Kind: And, Children: []*Node[uint]{c, NewConst[uint](true)}}, "c0 = 1", "c0 = 1"},
+	}
+
+	for i, tc := range cases {
+		dnf, err := NodeToDNF(tc.node, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dnf.String() != tc.dnf {
+			t.Errorf("invalid DNF in case %d, got %s, expected %s", i+1, dnf, tc.dnf)
+		}
+		cnf, err := NodeToCNF(tc.node, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cnf.String() != tc.cnf {
+			t.Errorf("invalid CNF in case %d, got %s, expected %s", i+1, cnf, tc.cnf)
+		}
+	}
+
+	if _, err := DNF[uint](nil).Expression(); err == nil {
+		t.Errorf("empty DNF can't be an expression")
+	}
+}
+
+func TestNormalFormsEquivalent(t *testing.T) {
+	const vars = 4
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		src := randomExpression(r, vars, 3)
+		orig := parse(t, src)
+		p, err := orig.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dnf, err := ToDNF(orig, 0)
+		if err != nil {
+			t.Fatalf("error converting %s: %v", src, err)
+		}
+		cnf, err := ToCNF(orig, 0)
+		if err != nil {
+			t.Fatalf("error converting %s: %v", src, err)
+		}
+
+		for _, form := range []interface {
+			Expression() (*parser.Expression[uint], error)
+			String() string
+		}{dnf, cnf} {
+			expr, err := form.Expression()
+			if err != nil {
+				t.Fatalf("error converting %s back: %v", form, err)
+			}
+			pf, err := expr.Compile(bitmaskCompiler)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for model := uint(0); model < 1<<vars; model++ {
+				if p.Evaluate(model) != pf.Evaluate(model) {
+					t.Fatalf("%s and its normal form %s differ for %04b", src, form, model)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Rewrite describes a single simplification made by the optimizer
+	Rewrite struct {
+		Rule        string
+		Description string
+		// Token points to the first condition of the affected part of the
+		// expression, it's nil for constants
+		Token *lexer.Token
+	}
+
+	// Explanation lists the rewrites in the order they were applied
+	Explanation []Rewrite
+
+	optimizer[T any] struct {
+		rewrites Explanation
+	}
+)
+
+func (r Rewrite) String() string {
+	if r.Token == nil {
+		return r.Rule + ": " + r.Description
+	}
+	return fmt.Sprintf("%s: %s at line %d pos %d", r.Rule, r.Description, r.Token.Line, r.Token.Position)
+}
+
+func (e Explanation) String() string {
+	lines := make([]string, len(e))
+	for i, r := range e {
+		lines[i] = r.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Optimize simplifies the expression without changing its result for any
+// model, matchers are assumed to have no side effects. The original
+// expression is left untouched, the result shares its conditions.
+func Optimize[T any](e *parser.Expression[T]) (*parser.Expression[T], Explanation, error) {
+	n, explanation := OptimizeNode(FromExpression(e))
+	expr, err := n.ToExpression()
+	if err != nil {
+		return nil, nil, err
+	}
+	return expr, explanation, nil
+}
+
+// OptimizeNode applies the rewrite rules until none of them changes the
+// tree anymore:
+//
+//   - fold: constants are folded into their parents
+//   - flatten: a child of the same kind as its parent is merged into it
+//   - unwrap: a node with a single child is replaced by the child
+//   - dedupe: equivalent children of a node are removed
+//   - absorb: a or (a and b) becomes a, a and (a or b) becomes a
+func OptimizeNode[T any](n *Node[T]) (*Node[T], Explanation) {
+	o := &optimizer[T]{}
+	for {
+		before := len(o.rewrites)
+		n = o.optimize(n)
+		if len(o.rewrites) == before {
+			return n, o.rewrites
+		}
+	}
+}
+
+func firstToken[T any](n *Node[T]) *lexer.Token {
+	conds := n.Conditions()
+	if len(conds) == 0 {
+		return nil
+	}
+	return conds[0].Identifier.Token
+}
+
+func (o *optimizer[T]) record(rule string, n *Node[T], format string, args ...any) {
+	o.rewrites = append(o.rewrites, Rewrite{rule, fmt.Sprintf(format, args...), firstToken(n)})
+}
+
+func (o *optimizer[T]) optimize(n *Node[T]) *Node[T] {
+	if n.Kind != And && n.Kind != Or {
+		return n
+	}
+
+	children := make([]*Node[T], len(n.Children))
+	for i, child := range n.Children {
+		children[i] = o.optimize(child)
+	}
+	n = &Node[T]{Kind: n.Kind, Children: children}
+
+	if folded := o.fold(n); folded != nil {
+		return folded
+	}
+	o.flatten(n)
+	o.dedupe(n)
+	o.absorb(n)
+
+	if len(n.Children) == 1 {
+		o.record("unwrap", n, "%s has a single operand", strings.ToLower(n.Kind.String()))
+		return n.Children[0]
+	}
+	return n
+}
+
+// fold removes neutral constants and returns the constant the whole node
+// folds to if there's one
+func (o *optimizer[T]) fold(n *Node[T]) *Node[T] {
+	// false for and, true for or decides the result
+	dominant, neutral := False, True
+	if n.Kind == Or {
+		dominant, neutral = True, False
+	}
+
+	children := n.Children[:0]
+	for _, child := range n.Children {
+		switch child.Kind {
+		case dominant:
+			o.record("fold", n, "%s is %s", n, strings.ToLower(dominant.String()))
+			return &Node[T]{Kind: dominant}
+		case neutral:
+			o.record("fold", n, "removed %s from %s", strings.ToLower(neutral.String()), n)
+		default:
+			children = append(children, child)
+		}
+	}
+	n.Children = children
+
+	if len(children) == 0 {
+		return &Node[T]{Kind: neutral}
+	}
+	return nil
+}
+
+func (o *optimizer[T]) flatten(n *Node[T]) {
+	var children []*Node[T]
+	for _, child := range n.Children {
+		if child.Kind == n.Kind {
+			o.record("flatten", child, "merged (%s) into the enclosing %s", child, strings.ToLower(n.Kind.String()))
+			children = append(children, child.Children...)
+		} else {
+			children = append(children, child)
+		}
+	}
+	n.Children = children
+}
+
+func (o *optimizer[T]) dedupe(n *Node[T]) {
+	seen := make(map[string]bool)
+	var children []*Node[T]
+	for _, child := range n.Children {
+		key := child.Key()
+		if seen[key] {
+			o.record("dedupe", child, "removed duplicate %s", child)
+			continue
+		}
+		seen[key] = true
+		children = append(children, child)
+	}
+	n.Children = children
+}
+
+// operandKeys returns the keys of the operands of the child as seen from
+// a parent of the given kind, i.e. the conjuncts of an and child of an or
+func operandKeys[T any](child *Node[T], parent Kind) map[string]bool {
+	inner := Or
+	if parent == Or {
+		inner = And
+	}
+
+	keys := make(map[string]bool)
+	if child.Kind == inner {
+		for _, c := range child.Children {
+			keys[c.Key()] = true
+		}
+	} else {
+		keys[child.Key()] = true
+	}
+	return keys
+}
+
+func subset(a, b map[string]bool) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// absorb removes children implied by a sibling: in an or, a child whose
+// conjuncts include all conjuncts of a sibling is redundant, and the same
+// goes for disjuncts in an and
+func (o *optimizer[T]) absorb(n *Node[T]) {
+	keys := make([]map[string]bool, len(n.Children))
+	for i, child := range n.Children {
+		keys[i] = operandKeys(child, n.Kind)
+	}
+
+	removed := make([]bool, len(n.Children))
+	for i := range n.Children {
+		for j := range n.Children {
+			if i == j || removed[j] || len(keys[j]) >= len(keys[i]) {
+				continue
+			}
+			if subset(keys[j], keys[i]) {
+				o.record("absorb", n.Children[i], "removed %s absorbed by %s", n.Children[i], n.Children[j])
+				removed[i] = true
+				break
+			}
+		}
+	}
+
+	var children []*Node[T]
+	for i, child := range n.Children {
+		if !removed[i] {
+			children = append(children, child)
+		}
+	}
+	n.Children = children
+}
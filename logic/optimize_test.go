@@ -0,0 +1,168 @@
+package logic
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type optimizeCase struct {
+	input  string
+	output string
+}
+
+var optimizeCases = []optimizeCase{
+	{`c0 = 1`, `c0 = 1`},
+	{`c0 = 1 and c0 = 1.0`, `c0 = 1`},
+	{`(c0 = 1 and c1 = 1) and c2 = 1`, `c0 = 1 and c1 = 1 and c2 = 1`},
+	{`c0 = 1 or (c0 = 1 and c1 = 1)`, `c0 = 1`},
+	{`(c1 = 1 and c0 = 1) or c0 = 1`, `c0 = 1`},
+	{`c0 = 1 and (c1 = 1 or c0 = 1)`, `c0 = 1`},
+	{`(c0 = 1 or c1 = 1) and (c1 = 1 or c0 = 1)`, `c0 = 1 or c1 = 1`},
+	{`(c0 = 1 and c1 = 1) or (c0 = 1 and c1 = 1 and c2 = 1) or c3 = 1`, `(c0 = 1 and c1 = 1) or c3 = 1`},
+	{`((c0 = 1))`, `c0 = 1`},
+	{`(c0 = 1 and c1 = 1) and c0 = 1`, `c0 = 1 and c1 = 1`},
+	{`((c0 = 1 or c1 = 1) or c2 = 1) or c1 = 1`, `c0 = 1 or c1 = 1 or c2 = 1`},
+}
+
+func TestFromExpression(t *testing.T) {
+	cases := map[string]string{
+		`(c0 = 1 and c1 = 1) and c2 = 1`:           `and(c0 = 1, c1 = 1, c2 = 1)`,
+		`c0 = 1 and (c1 = 1 and (c2 = 1))`:         `and(c0 = 1, c1 = 1, c2 = 1)`,
+		`((c0 = 1 or c1 = 1) or c2 = 1) or c3 = 1`: `or(c0 = 1, c1 = 1, c2 = 1, c3 = 1)`,
+		`(c0 = 1 or c1 = 1) and c2 = 1`:            `and(c2 = 1, or(c0 = 1, c1 = 1))`,
+	}
+	for src, exp := range cases {
+		if key := FromExpression(parse(t, src)).Key(); key != exp {
+			t.Errorf("%s got %s, expected %s", src, key, exp)
+		}
+	}
+}
+
+func parse(t testing.TB, src string) *parser.Expression[uint] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[uint](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+func formatExpr[T any](t testing.TB, e *parser.Expression[T], opts parser.FormatOptions) string {
+	t.Helper()
+	result, err := e.Format(opts)
+	if err != nil {
+		t.Fatalf("error formatting %s: %v", e, err)
+	}
+	return result
+}
+
+func bitmaskCompiler(c *parser.Condition[uint]) (parser.Matcher[uint], error) {
+	bit, err := strconv.Atoi(strings.TrimPrefix(c.Identifier.Name, "c"))
+	if err != nil {
+		return nil, err
+	}
+	return func(model uint) bool { return model&(1<<bit) != 0 }, nil
+}
+
+func TestOptimize(t *testing.T) {
+	for i, tc := range optimizeCases {
+		expr, _, err := Optimize(parse(t, tc.input))
+		if err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if result := formatExpr(t, expr, parser.FormatOptions{}); result != tc.output {
+			t.Errorf("invalid result in case %d, got %s, expected %s", i+1, result, tc.output)
+		}
+	}
+}
+
+func TestOptimizeExplain(t *testing.T) {
+	_, explanation, err := Optimize(parse(t, `(c0 = 1 and c1 = 1) and c0 = 1 or c2 = 1 or (c2 = 1 and c3 = 1)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := strings.Join([]string{
+		"absorb: removed c2 = 1 and c3 = 1 absorbed by c2 = 1 at line 1 pos 46",
+		"absorb: removed c0 = 1 or c2 = 1 absorbed by c0 = 1 at line 1 pos 25",
+	}, "\n")
+	if explanation.String() != exp {
+		t.Errorf("invalid explanation, got\n%s\nexpected\n%s", explanation, exp)
+	}
+}
+
+func TestOptimizeConstants(t *testing.T) {
+	c := NewCondition(parse(t, `c0 = 1`).Left.Condition)
+
+	cases := []struct {
+		node   *Node[uint]
+		result string
+	}{
+		{&Node[uint]{Kind: And, Children: []*Node[uint]{c, NewConst[uint](true)}}, "c0 = 1"},
+		{&Node[uint]{Kind: And, Children: []*Node[uint]{c, NewConst[uint](false)}}, "false"},
+		{&Node[uint]{Kind: Or, Children: []*Node[uint]{c, NewConst[uint](true)}}, "true"},
+		{&Node[uint]{Kind: Or, Children: []*Node[uint]{NewConst[uint](false), NewConst[uint](false)}}, "false"},
+	}
+
+	for i, tc := range cases {
+		n, _ := OptimizeNode(tc.node)
+		if n.String() != tc.result {
+			t.Errorf("invalid result in case %d, got %s, expected %s", i+1, n, tc.result)
+		}
+	}
+}
+
+func randomExpression(r *rand.Rand, n int, depth int) string {
+	var sb strings.Builder
+	operands := r.Intn(4) + 1
+	for i := 0; i < operands; i++ {
+		if i > 0 {
+			sb.WriteString([]string{" and ", " or "}[r.Intn(2)])
+		}
+		if depth > 0 && r.Intn(3) == 0 {
+			sb.WriteString("(" + randomExpression(r, n, depth-1) + ")")
+		} else {
+			sb.WriteString("c" + strconv.Itoa(r.Intn(n)) + " = 1")
+		}
+	}
+	return sb.String()
+}
+
+func TestOptimizeEquivalent(t *testing.T) {
+	const vars = 4
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		src := randomExpression(r, vars, 3)
+		orig := parse(t, src)
+		optimized, _, err := Optimize(orig)
+		if err != nil {
+			t.Fatalf("error optimizing %s: %v", src, err)
+		}
+
+		p1, err := orig.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := optimized.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for model := uint(0); model < 1<<vars; model++ {
+			if p1.Evaluate(model) != p2.Evaluate(model) {
+				t.Fatalf("%s and its optimized form %s differ for %04b", src, formatExpr(t, optimized, parser.FormatOptions{}), model)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package logic
+
+import (
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+// PartialEvaluate resolves the conditions on known identifiers with the
+// match semantics and returns what's left of the expression: a tree of the
+// remaining conditions, or a constant if the known values decide the
+// result. Conditions with placeholders are left as they are. The result
+// shares conditions with the expression and can be converted back with
+// ToExpression unless it's a constant.
+func PartialEvaluate[T any](e *parser.Expression[T], known map[string]any) (*Node[T], error) {
+	return PartialEvaluateNode(FromExpression(e), known)
+}
+
+// PartialEvaluateNode is PartialEvaluate for trees which may contain
+// constants
+func PartialEvaluateNode[T any](n *Node[T], known map[string]any) (*Node[T], error) {
+	switch n.Kind {
+	case True, False:
+		return n, nil
+	case Cond:
+		value, found := known[n.Condition.Identifier.Name]
+		if !found || n.Condition.Value.IsPlaceholder() {
+			return n, nil
+		}
+		pred, err := match.NewPredicate(n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return NewConst[T](pred(value)), nil
+	}
+
+	// false for and, true for or decides the result
+	dominant := False
+	if n.Kind == Or {
+		dominant = True
+	}
+
+	var children []*Node[T]
+	for _, child := range n.Children {
+		reduced, err := PartialEvaluateNode(child, known)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case reduced.Kind == dominant:
+			return reduced, nil
+		case reduced.IsConst():
+			continue
+		case reduced.Kind == n.Kind:
+			children = append(children, reduced.Children...)
+		default:
+			children = append(children, reduced)
+		}
+	}
+
+	switch len(children) {
+	case 0:
+		return NewConst[T](n.Kind == And), nil
+	case 1:
+		return children[0], nil
+	}
+	return &Node[T]{Kind: n.Kind, Children: children}, nil
+}
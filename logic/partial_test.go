@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type partialCase struct {
+	input  string
+	known  map[string]any
+	output string
+}
+
+var partialCases = []partialCase{
+	{`facility = "TWR" and callsign =~ "^EG"`, map[string]any{"facility": "TWR"}, `callsign =~ "^EG"`},
+	{`facility = "TWR" and callsign =~ "^EG"`, map[string]any{"facility": "APP"}, `false`},
+	{`facility = "TWR" or rating > 3`, map[string]any{"facility": "TWR"}, `true`},
+	{`facility = "TWR" or rating > 3`, map[string]any{"facility": "APP"}, `rating > 3`},
+	{
+		`(facility = "TWR" and rating > 3) or (facility = "APP" and rating > 5)`,
+		map[string]any{"facility": "TWR"},
+		`rating > 3`,
+	},
+	{
+		`facility = "TWR" and (rating > 3 and (rating < 10 or x = 1))`,
+		map[string]any{"facility": "TWR", "x": 2},
+		`rating > 3 and rating < 10`,
+	},
+	{`facility != "TWR" and x = 1`, map[string]any{"facility": nil}, `x = 1`},
+	{`rating > 3`, map[string]any{"rating": 5}, `true`},
+	{`rating > 3`, map[string]any{"rating": "5"}, `false`},
+	{`facility = $f and rating > 3`, map[string]any{"facility": "TWR", "rating": 4}, `facility = $f`},
+	{`facility = "TWR"`, map[string]any{}, `facility = "TWR"`},
+}
+
+func TestPartialEvaluate(t *testing.T) {
+	for i, tc := range partialCases {
+		n, err := PartialEvaluate(parse(t, tc.input), tc.known)
+		if err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if n.String() != tc.output {
+			t.Errorf("invalid result in case %d, got %s, expected %s", i+1, n, tc.output)
+		}
+	}
+
+	_, err := PartialEvaluate(parse(t, `x = 1 or facility =~ "("`), map[string]any{"facility": "TWR"})
+	if err == nil || err.Error() != "invalid regular expression: error parsing regexp: missing closing ): `(` at line 1 pos 22" {
+		t.Errorf("got %v, expected the regular expression error", err)
+	}
+}
+
+func TestPartialEvaluateEquivalent(t *testing.T) {
+	domain := []any{nil, 0.0, 1.0, 1.5, 2.0, 2.5, 3.0, 4.0, "", "a", "ab", "b", "c"}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		src := randomFilter(r, 2)
+		p := compileModel(t, src)
+
+		for _, x := range domain {
+			n, err := PartialEvaluate(p.Expression(), map[string]any{"x": x})
+			if err != nil {
+				t.Fatalf("error evaluating %s: %v", src, err)
+			}
+
+			var residual *parser.Program[map[string]any]
+			if !n.IsConst() {
+				expr, err := n.ToExpression()
+				if err != nil {
+					t.Fatal(err)
+				}
+				residual = compileModel(t, formatExpr(t, expr, parser.FormatOptions{}))
+			}
+			for _, y := range domain {
+				m := map[string]any{"x": x, "y": y}
+				result := n.Kind == True
+				if residual != nil {
+					result = residual.Evaluate(m)
+				}
+				if result != p.Evaluate(m) {
+					t.Fatalf("%s with x = %v reduced to %s differs for y = %v", src, x, n, y)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package logic
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vatsimnerd/lee/parser"
+)
+
+//go:generate stringer -type=Verdict
+
+// Verdict tells whether a filter can match anything
+type Verdict int
+
+const (
+	// Unknown means the analysis found no contradiction but couldn't prove
+	// the filter satisfiable either, i.e. it has placeholders, several
+	// patterns for the same field or is too large to normalize
+	Unknown Verdict = iota
+	Satisfiable
+	Unsatisfiable
+)
+
+type (
+	// Conflict is a set of conditions which can't hold together
+	Conflict[T any] struct {
+		Reason     string
+		Conditions []*parser.Condition[T]
+	}
+
+	// Analysis is the result of Analyze. Conflicts lists the contradictions
+	// of every and-branch which can never match, so a satisfiable filter
+	// may still have conflicts in some of its branches.
+	Analysis[T any] struct {
+		Verdict   Verdict
+		Conflicts []Conflict[T]
+	}
+)
+
+// Spans returns the source ranges of the conflicting conditions
+func (c Conflict[T]) Spans() []parser.Span {
+	spans := make([]parser.Span, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		spans[i] = cond.Span()
+	}
+	return spans
+}
+
+func (c Conflict[T]) String() string {
+	parts := make([]string, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		parts[i] = cond.Format()
+		if span := cond.Span(); !span.IsZero() {
+			parts[i] += fmt.Sprintf(" at line %d pos %d", span.Line, span.Position)
+		}
+	}
+	return c.Reason + ": " + strings.Join(parts, ", ")
+}
+
+func (a Analysis[T]) String() string {
+	lines := []string{strings.ToLower(a.Verdict.String())}
+	for _, c := range a.Conflicts {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Analyze decides whether the filter can ever match. Numeric conditions on
+// the same field are checked as intervals, string ones as intervals and
+// sets of excluded values, patterns are checked against the string a field
+// must be equal to. A field can't be a number and a string at once.
+//
+// The analysis doesn't know field types, so a satisfiable filter may still
+// never match models whose fields have other types. Patterns are assumed to
+// match some string.
+func Analyze[T any](e *parser.Expression[T]) Analysis[T] {
+	return AnalyzeNode(FromExpression(e))
+}
+
+// AnalyzeNode is Analyze for trees which may contain constants
+func AnalyzeNode[T any](n *Node[T]) Analysis[T] {
+	dnf, err := NodeToDNF(n, DefaultMaxTerms)
+	if err != nil {
+		return Analysis[T]{Verdict: Unknown}
+	}
+
+	a := Analysis[T]{Verdict: Unsatisfiable}
+	seen := make(map[string]bool)
+	undecided := false
+	for _, conj := range dnf {
+		c := newConstraints[T]()
+		conflict := c.addAll(conj)
+		if conflict == nil {
+			if c.decided() {
+				a.Verdict = Satisfiable
+			} else {
+				undecided = true
+			}
+			continue
+		}
+
+		key := conflict.key()
+		if !seen[key] {
+			seen[key] = true
+			a.Conflicts = append(a.Conflicts, *conflict)
+		}
+	}
+	if a.Verdict == Unsatisfiable && undecided {
+		a.Verdict = Unknown
+	}
+	return a
+}
+
+func (c Conflict[T]) key() string {
+	parts := make([]string, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		parts[i] = fmt.Sprintf("%p", cond)
+	}
+	return strings.Join(parts, " ")
+}
+
+func newConflict[T any](reason string, conds ...*parser.Condition[T]) *Conflict[T] {
+	var unique []*parser.Condition[T]
+	for _, cond := range conds {
+		found := false
+		for _, u := range unique {
+			found = found || u == cond
+		}
+		if !found {
+			unique = append(unique, cond)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		a, b := unique[i].Span(), unique[j].Span()
+		return a.Line < b.Line || a.Line == b.Line && a.Position < b.Position
+	})
+	return &Conflict[T]{Reason: reason, Conditions: unique}
+}
+
+type (
+	ordered interface {
+		~float64 | ~string
+	}
+
+	bound[T any, V ordered] struct {
+		value V
+		open  bool
+		cond  *parser.Condition[T]
+	}
+
+	exclusion[T any, V ordered] struct {
+		value V
+		cond  *parser.Condition[T]
+	}
+
+	// interval is the range of values allowed by the conditions, nil
+	// bounds are unlimited
+	interval[T any, V ordered] struct {
+		lo, hi   *bound[T, V]
+		excluded []exclusion[T, V]
+	}
+
+	pattern[T any] struct {
+		re   *regexp.Regexp
+		cond *parser.Condition[T]
+	}
+
+	// field collects the constraints of a conjunction on one identifier
+	field[T any] struct {
+		name string
+		// number and str are the first conditions requiring the type
+		number, str *parser.Condition[T]
+		nums        interval[T, float64]
+		strs        interval[T, string]
+		patterns    []pattern[T]
+		// guarded are orderings which only apply to values of their type
+		guarded   []*parser.Condition[T]
+		undecided bool
+	}
+
+	constraints[T any] struct {
+		fields map[string]*field[T]
+	}
+)
+
+func (iv *interval[T, V]) add(op parser.OperatorType, value V, cond *parser.Condition[T]) {
+	switch op {
+	case parser.Equals:
+		iv.lower(value, false, cond)
+		iv.upper(value, false, cond)
+	case parser.Greater, parser.GreaterOrEqual:
+		iv.lower(value, op == parser.Greater, cond)
+	case parser.Less, parser.LessOrEqual:
+		iv.upper(value, op == parser.Less, cond)
+	case parser.NotEquals:
+		iv.excluded = append(iv.excluded, exclusion[T, V]{value, cond})
+	}
+}
+
+func (iv *interval[T, V]) lower(value V, open bool, cond *parser.Condition[T]) {
+	if iv.lo == nil || value > iv.lo.value || value == iv.lo.value && open && !iv.lo.open {
+		iv.lo = &bound[T, V]{value, open, cond}
+	}
+}
+
+func (iv *interval[T, V]) upper(value V, open bool, cond *parser.Condition[T]) {
+	if iv.hi == nil || value < iv.hi.value || value == iv.hi.value && open && !iv.hi.open {
+		iv.hi = &bound[T, V]{value, open, cond}
+	}
+}
+
+// point returns the only value the interval allows
+func (iv *interval[T, V]) point() (V, bool) {
+	if iv.lo == nil || iv.hi == nil || iv.lo.open || iv.hi.open || iv.lo.value != iv.hi.value {
+		var zero V
+		return zero, false
+	}
+	return iv.lo.value, true
+}
+
+func (iv *interval[T, V]) bounded() bool {
+	return iv.lo != nil || iv.hi != nil || len(iv.excluded) > 0
+}
+
+func (iv *interval[T, V]) check(name string) *Conflict[T] {
+	if iv.lo != nil && iv.hi != nil {
+		lo, hi := iv.lo, iv.hi
+		if lo.value > hi.value || lo.value == hi.value && (lo.open || hi.open) {
+			reason := "conflicting bounds on " + name
+			if lo.cond.Operator.Type == parser.Equals && hi.cond.Operator.Type == parser.Equals {
+				reason = "conflicting values of " + name
+			}
+			return newConflict(reason, lo.cond, hi.cond)
+		}
+	}
+	if value, ok := iv.point(); ok {
+		for _, ex := range iv.excluded {
+			if ex.value == value {
+				return newConflict("excluded value of "+name, iv.lo.cond, iv.hi.cond, ex.cond)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *field[T]) add(cond *parser.Condition[T]) {
+	v := cond.Value
+	op := cond.Operator.Type
+	if v.IsPlaceholder() {
+		f.undecided = true
+		return
+	}
+
+	switch {
+	case op == parser.Matches || op == parser.NotMatches:
+		src, err := v.GetUnquotedStringValue()
+		if err != nil {
+			f.undecided = true
+			return
+		}
+		re, err := regexp.Compile(src)
+		if err != nil {
+			f.undecided = true
+			return
+		}
+		f.patterns = append(f.patterns, pattern[T]{re, cond})
+		if op == parser.Matches && f.str == nil {
+			f.str = cond
+		}
+	case v.IsFloat():
+		f.nums.add(op, *v.Number, cond)
+		if op != parser.NotEquals && f.number == nil {
+			f.number = cond
+		}
+	default:
+		str, err := v.GetUnquotedStringValue()
+		if err != nil {
+			f.undecided = true
+			return
+		}
+		f.strs.add(op, str, cond)
+		if op != parser.NotEquals && f.str == nil {
+			f.str = cond
+		}
+	}
+}
+
+// addNegated constrains the field by the negation of the condition. The
+// negation of an ordering is satisfied by any value of another type, so
+// it's only applied once the field is known to have the type of its value.
+func (f *field[T]) addNegated(cond *parser.Condition[T]) {
+	if cond.Value.IsPlaceholder() {
+		// leaving a condition out never makes a conflict appear
+		return
+	}
+
+	op := *cond.Operator
+	neg := &parser.Condition[T]{Identifier: cond.Identifier, Operator: &op, Value: cond.Value}
+	switch op.Type {
+	case parser.Equals:
+		op.Type = parser.NotEquals
+	case parser.NotEquals:
+		op.Type = parser.Equals
+	case parser.Matches:
+		op.Type = parser.NotMatches
+	case parser.NotMatches:
+		op.Type = parser.Matches
+	case parser.Less:
+		op.Type = parser.GreaterOrEqual
+	case parser.LessOrEqual:
+		op.Type = parser.Greater
+	case parser.Greater:
+		op.Type = parser.LessOrEqual
+	case parser.GreaterOrEqual:
+		op.Type = parser.Less
+	}
+
+	switch op.Type {
+	case parser.Less, parser.LessOrEqual, parser.Greater, parser.GreaterOrEqual:
+		f.guarded = append(f.guarded, neg)
+	default:
+		f.add(neg)
+	}
+}
+
+func (f *field[T]) check() *Conflict[T] {
+	if f.number != nil && f.str != nil {
+		return newConflict("conflicting types of "+f.name, f.number, f.str)
+	}
+
+	nums, strs := f.nums, f.strs
+	for _, g := range f.guarded {
+		if g.Value.IsFloat() && f.number != nil {
+			nums.add(g.Operator.Type, *g.Value.Number, g)
+		} else if str, err := g.Value.GetUnquotedStringValue(); err == nil && f.str != nil {
+			strs.add(g.Operator.Type, str, g)
+		}
+	}
+	if conflict := nums.check(f.name); conflict != nil {
+		return conflict
+	}
+	if conflict := strs.check(f.name); conflict != nil {
+		return conflict
+	}
+
+	for i, p := range f.patterns {
+		for _, other := range f.patterns[:i] {
+			if p.re.String() == other.re.String() && p.cond.Operator.Type != other.cond.Operator.Type {
+				return newConflict("conflicting patterns on "+f.name, other.cond, p.cond)
+			}
+		}
+	}
+
+	value, ok := strs.point()
+	if !ok {
+		return nil
+	}
+	for _, p := range f.patterns {
+		if p.re.MatchString(value) != (p.cond.Operator.Type == parser.Matches) {
+			return newConflict("pattern mismatch on "+f.name, strs.lo.cond, strs.hi.cond, p.cond)
+		}
+	}
+	return nil
+}
+
+// decided reports whether a field without conflicts is known to have a
+// value satisfying all of its conditions
+func (f *field[T]) decided() bool {
+	if f.undecided {
+		return false
+	}
+	if _, ok := f.strs.point(); ok || len(f.patterns) == 0 {
+		return true
+	}
+	if f.str == nil {
+		// only negated patterns, which any non-string value satisfies
+		return true
+	}
+	return len(f.patterns) == 1 && f.patterns[0].cond.Operator.Type == parser.Matches && !f.strs.bounded()
+}
+
+func newConstraints[T any]() *constraints[T] {
+	return &constraints[T]{fields: make(map[string]*field[T])}
+}
+
+func (c *constraints[T]) field(name string) *field[T] {
+	f, found := c.fields[name]
+	if !found {
+		f = &field[T]{name: name}
+		c.fields[name] = f
+	}
+	return f
+}
+
+// add returns the conflict the condition makes with the previous ones
+func (c *constraints[T]) add(cond *parser.Condition[T]) *Conflict[T] {
+	f := c.field(cond.Identifier.Name)
+	f.add(cond)
+	return f.check()
+}
+
+// addNegated returns the conflict the negation of the condition makes with
+// the previous conditions
+func (c *constraints[T]) addNegated(cond *parser.Condition[T]) *Conflict[T] {
+	f := c.field(cond.Identifier.Name)
+	f.addNegated(cond)
+	return f.check()
+}
+
+func (c *constraints[T]) addAll(conds []*parser.Condition[T]) *Conflict[T] {
+	for _, cond := range conds {
+		if conflict := c.add(cond); conflict != nil {
+			return conflict
+		}
+	}
+	return nil
+}
+
+func (c *constraints[T]) decided() bool {
+	for _, f := range c.fields {
+		if !f.decided() {
+			return false
+		}
+	}
+	return true
+}
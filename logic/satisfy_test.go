@@ -0,0 +1,123 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+)
+
+type analyzeCase struct {
+	input  string
+	output string
+}
+
+var analyzeCases = []analyzeCase{
+	{
+		`altitude > 5000 and altitude < 3000`,
+		"unsatisfiable\nconflicting bounds on altitude: altitude > 5000 at line 1 pos 1, altitude < 3000 at line 1 pos 21",
+	},
+	{
+		`callsign = "A" and callsign = "B"`,
+		`unsatisfiable` + "\n" + `conflicting values of callsign: callsign = "A" at line 1 pos 1, callsign = "B" at line 1 pos 20`,
+	},
+	{
+		`x = 1 and x != 1`,
+		"unsatisfiable\nexcluded value of x: x = 1 at line 1 pos 1, x != 1 at line 1 pos 11",
+	},
+	{
+		`x >= 1 and y = 2 and x <= 1 and x != 1`,
+		"unsatisfiable\nexcluded value of x: x >= 1 at line 1 pos 1, x <= 1 at line 1 pos 22, x != 1 at line 1 pos 33",
+	},
+	{
+		`x = 1 and x < "b"`,
+		`unsatisfiable` + "\n" + `conflicting types of x: x = 1 at line 1 pos 1, x < "b" at line 1 pos 11`,
+	},
+	{
+		`callsign = "AFR1" and callsign =~ "^BAW"`,
+		`unsatisfiable` + "\n" + `pattern mismatch on callsign: callsign = "AFR1" at line 1 pos 1, callsign =~ "^BAW" at line 1 pos 23`,
+	},
+	{
+		`x = 1 and (x = 2 or x = 3)`,
+		"unsatisfiable\nconflicting values of x: x = 1 at line 1 pos 1, x = 2 at line 1 pos 12\nconflicting values of x: x = 1 at line 1 pos 1, x = 3 at line 1 pos 21",
+	},
+	{
+		`x > 1 and x < 1 or y = 2`,
+		"satisfiable\nconflicting bounds on x: x > 1 at line 1 pos 1, x < 1 at line 1 pos 11",
+	},
+	{
+		`x > 1 and x < 2 and x != 1.5`,
+		"satisfiable",
+	},
+	{
+		`x != 1 and x != "a" and x !~ "b"`,
+		"satisfiable",
+	},
+	{
+		`x >= "a" and x <= "a" and x !~ "b"`,
+		"satisfiable",
+	},
+	{
+		`x =~ "^A" and x = 1`,
+		`unsatisfiable` + "\n" + `conflicting types of x: x =~ "^A" at line 1 pos 1, x = 1 at line 1 pos 15`,
+	},
+	{
+		`x =~ "^A"`,
+		"satisfiable",
+	},
+	{
+		`x =~ "^A" and x !~ "^A"`,
+		`unsatisfiable` + "\n" + `conflicting patterns on x: x =~ "^A" at line 1 pos 1, x !~ "^A" at line 1 pos 15`,
+	},
+	{
+		`x =~ "^A" and x =~ "B$"`,
+		"unknown",
+	},
+	{
+		`x = $a and x = 1`,
+		"unknown",
+	},
+	{
+		`x = $a and y = 1 and y = 2`,
+		"unsatisfiable\nconflicting values of y: y = 1 at line 1 pos 12, y = 2 at line 1 pos 22",
+	},
+}
+
+func TestAnalyze(t *testing.T) {
+	for i, tc := range analyzeCases {
+		a := Analyze(parse(t, tc.input))
+		if a.String() != tc.output {
+			t.Errorf("invalid analysis in case %d, got\n%s\nexpected\n%s", i+1, a, tc.output)
+		}
+	}
+}
+
+func TestAnalyzeSpans(t *testing.T) {
+	a := Analyze(parse(t, "a = 1 and\nb = \"x\ny\" and b = \"z\""))
+	if len(a.Conflicts) != 1 {
+		t.Fatalf("expected one conflict, got %d", len(a.Conflicts))
+	}
+
+	spans := a.Conflicts[0].Spans()
+	exp := []string{
+		"line 2 pos 1 to line 3 pos 3",
+		"line 3 pos 8 to line 3 pos 15",
+	}
+	if len(spans) != len(exp) {
+		t.Fatalf("got %d spans, expected %d", len(spans), len(exp))
+	}
+	for i, span := range spans {
+		if span.String() != exp[i] {
+			t.Errorf("invalid span %d, got %s, expected %s", i, span, exp[i])
+		}
+	}
+}
+
+func TestAnalyzeLarge(t *testing.T) {
+	parts := make([]string, 12)
+	for i := range parts {
+		parts[i] = "(c0 = 1 or c1 = 1)"
+	}
+	src := strings.Join(parts, " and ")
+	if a := Analyze(parse(t, src)); a.Verdict != Unknown {
+		t.Errorf("too large filters must be unknown, got %s", a.Verdict)
+	}
+}
@@ -0,0 +1,182 @@
+// Package match implements comparisons of dynamically typed field values
+// against conditions. It's the reference semantics shared by the Go
+// compilers and the exporters mirroring them in other languages.
+//
+// A number condition only matches numeric fields of any Go numeric type or
+// json.Number, a string condition only matches string fields. Strings are
+// ordered by bytes. A field of another type, including nil, never satisfies
+// =, =~ or an ordering, and always satisfies != and !~ which are strict
+// negations of = and =~.
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type (
+	// Predicate tests a field value
+	Predicate func(field any) bool
+
+	// Accessor extracts a field value from a model
+	Accessor[T any] func(model T) any
+)
+
+// ToFloat converts any numeric value to float64
+func ToFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// NewPredicate builds a predicate for the condition operator and value
+func NewPredicate[T any](c *parser.Condition[T]) (Predicate, error) {
+	if c.Value.IsPlaceholder() {
+		return nil, lexer.ErrorAt(c.Value.Token, "unbound parameter %s", *c.Value.Placeholder)
+	}
+
+	switch c.Operator.Type {
+	case parser.Matches, parser.NotMatches:
+		pattern, err := c.Value.GetUnquotedStringValue()
+		if err != nil {
+			return nil, lexer.ErrorAt(c.Value.Token, "regular expression must be a string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, lexer.ErrorAt(c.Value.Token, "invalid regular expression: %v", err)
+		}
+		matches := func(field any) bool {
+			str, ok := field.(string)
+			return ok && re.MatchString(str)
+		}
+		if c.Operator.Type == parser.NotMatches {
+			return func(field any) bool { return !matches(field) }, nil
+		}
+		return matches, nil
+	}
+
+	if c.Value.IsFloat() {
+		return numberPredicate(c.Operator.Type, *c.Value.Number)
+	}
+
+	value, err := c.Value.GetUnquotedStringValue()
+	if err != nil {
+		return nil, lexer.ErrorAt(c.Value.Token, "invalid string value")
+	}
+	return stringPredicate(c.Operator.Type, value)
+}
+
+func numberPredicate(op parser.OperatorType, value float64) (Predicate, error) {
+	var cmp func(f float64) bool
+	switch op {
+	case parser.Equals, parser.NotEquals:
+		cmp = func(f float64) bool { return f == value }
+	case parser.Less:
+		cmp = func(f float64) bool { return f < value }
+	case parser.LessOrEqual:
+		cmp = func(f float64) bool { return f <= value }
+	case parser.Greater:
+		cmp = func(f float64) bool { return f > value }
+	case parser.GreaterOrEqual:
+		cmp = func(f float64) bool { return f >= value }
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", op)
+	}
+
+	pred := func(field any) bool {
+		f, ok := ToFloat(field)
+		return ok && cmp(f)
+	}
+	if op == parser.NotEquals {
+		return func(field any) bool { return !pred(field) }, nil
+	}
+	return pred, nil
+}
+
+func stringPredicate(op parser.OperatorType, value string) (Predicate, error) {
+	var cmp func(s string) bool
+	switch op {
+	case parser.Equals, parser.NotEquals:
+		cmp = func(s string) bool { return s == value }
+	case parser.Less:
+		cmp = func(s string) bool { return s < value }
+	case parser.LessOrEqual:
+		cmp = func(s string) bool { return s <= value }
+	case parser.Greater:
+		cmp = func(s string) bool { return s > value }
+	case parser.GreaterOrEqual:
+		cmp = func(s string) bool { return s >= value }
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", op)
+	}
+
+	pred := func(field any) bool {
+		s, ok := field.(string)
+		return ok && cmp(s)
+	}
+	if op == parser.NotEquals {
+		return func(field any) bool { return !pred(field) }, nil
+	}
+	return pred, nil
+}
+
+// Compiler returns a compilation callback reading identifiers with the
+// given accessors, unknown identifiers fail the compilation
+func Compiler[T any](accessors map[string]Accessor[T]) parser.CompilationCallback[T] {
+	return func(c *parser.Condition[T]) (parser.Matcher[T], error) {
+		accessor, found := accessors[c.Identifier.Name]
+		if !found {
+			return nil, lexer.ErrorAt(c.Identifier.Token, "unknown identifier %s", c.Identifier.Name)
+		}
+
+		pred, err := NewPredicate(c)
+		if err != nil {
+			return nil, err
+		}
+		return func(model T) bool { return pred(accessor(model)) }, nil
+	}
+}
+
+// Fields returns a field function reading identifiers with the given
+// accessors, for explanations of programs compiled by Compiler
+func Fields[T any](accessors map[string]Accessor[T]) parser.FieldFunc[T] {
+	return func(model T, identifier string) (any, bool) {
+		accessor, found := accessors[identifier]
+		if !found {
+			return nil, false
+		}
+		return accessor(model), true
+	}
+}
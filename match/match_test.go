@@ -0,0 +1,80 @@
+package match
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+type testcase struct {
+	condition string
+	field     any
+	result    bool
+}
+
+var cases = []testcase{
+	{`a = 5`, 5, true},
+	{`a = 5`, uint8(5), true},
+	{`a = 5`, json.Number("5.0"), true},
+	{`a = 5`, "5", false},
+	{`a != 5`, "5", true},
+	{`a != 5`, nil, true},
+	{`a < 5.5`, int64(5), true},
+	{`a >= 5.5`, float32(5.5), true},
+	{`a = "x"`, "x", true},
+	{`a = 'x'`, 1, false},
+	{`a < "b"`, "a", true},
+	{`a > "b"`, "a", false},
+	{`a =~ "^AF"`, "AFL123", true},
+	{`a =~ "^AF"`, nil, false},
+	{`a !~ "^AF"`, nil, true},
+	{`a !~ "^AF"`, "SU", true},
+}
+
+func condition(t *testing.T, src string) *parser.Condition[any] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[any](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr.Left.Condition
+}
+
+func TestPredicate(t *testing.T) {
+	for i, tc := range cases {
+		pred, err := NewPredicate(condition(t, tc.condition))
+		if err != nil {
+			t.Errorf("unexpected error in case %d: %v", i+1, err)
+			continue
+		}
+		if pred(tc.field) != tc.result {
+			t.Errorf("%s with %#v should be %v", tc.condition, tc.field, tc.result)
+		}
+	}
+}
+
+func TestPredicateErrors(t *testing.T) {
+	cases := map[string]string{
+		`a =~ 5`:       "regular expression must be a string at line 1 pos 6",
+		`a =~ "(a"`:    "invalid regular expression: error parsing regexp: missing closing ): `(a` at line 1 pos 6",
+		`a !~ '[z-a]'`: "invalid regular expression: error parsing regexp: invalid character class range: `z-a` at line 1 pos 6",
+		`a = $b`:       "unbound parameter b at line 1 pos 5",
+	}
+
+	for src, exp := range cases {
+		_, err := NewPredicate(condition(t, src))
+		if err == nil {
+			t.Errorf("%s should fail", src)
+			continue
+		}
+		if err.Error() != exp {
+			t.Errorf("should throw %v, but throws %v", exp, err)
+		}
+	}
+}
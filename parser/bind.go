@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+// validName reports whether the string is a valid placeholder or macro
+// name, i.e. an identifier without dots
+func validName(name string) bool {
+	return lexer.Is(lexer.Placeholder, "$"+name)
+}
+
+// Params returns the sorted names of the placeholders in the expression
+func (e *Expression[T]) Params() []string {
+	seen := make(map[string]bool)
+	e.params(seen)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Expression[T]) params(seen map[string]bool) {
+	if e.Left.Condition != nil {
+		if v := e.Left.Condition.Value; v.IsPlaceholder() {
+			seen[*v.Placeholder] = true
+		}
+	} else {
+		e.Left.Grouping.Expression.params(seen)
+	}
+	if e.Right != nil {
+		e.Right.params(seen)
+	}
+}
+
+// Bind returns a copy of the expression with every placeholder replaced by
+// the value of its parameter, which must be a string or a number. Missing
+// parameters are errors, extra ones are ignored. Conditions without
+// placeholders are shared with the original expression.
+func (e *Expression[T]) Bind(params map[string]any) (*Expression[T], error) {
+	return e.bind(params, make(map[*Condition[T]]*Condition[T]))
+}
+
+// bind records the replaced conditions in bound
+func (e *Expression[T]) bind(params map[string]any, bound map[*Condition[T]]*Condition[T]) (*Expression[T], error) {
+	b := &Expression[T]{Left: &LeftExpression[T]{}, Operator: e.Operator}
+
+	if cond := e.Left.Condition; cond != nil {
+		b.Left.Condition = cond
+		if cond.Value.IsPlaceholder() {
+			value, err := bindValue(cond.Value, params)
+			if err != nil {
+				return nil, err
+			}
+			b.Left.Condition = &Condition[T]{Identifier: cond.Identifier, Operator: cond.Operator, Value: value}
+			bound[cond] = b.Left.Condition
+		}
+	} else {
+		inner, err := e.Left.Grouping.Expression.bind(params, bound)
+		if err != nil {
+			return nil, err
+		}
+		b.Left.Grouping = &Grouping[T]{Expression: inner, Reference: e.Left.Grouping.Reference}
+	}
+
+	if e.Right != nil {
+		right, err := e.Right.bind(params, bound)
+		if err != nil {
+			return nil, err
+		}
+		b.Right = right
+	}
+	return b, nil
+}
+
+// bindValue makes a literal value from the parameter, its token keeps
+// the position of the placeholder
+func bindValue(v *Value, params map[string]any) (*Value, error) {
+	name := *v.Placeholder
+	param, found := params[name]
+	if !found {
+		return nil, lexer.ErrorAt(v.Token, "missing parameter %s", name)
+	}
+
+	tok := &lexer.Token{}
+	if v.Token != nil {
+		tok.Line, tok.Position = v.Token.Line, v.Token.Position
+	}
+
+	if str, ok := param.(string); ok {
+		// the literal can't represent a trailing backslash, the value
+		// itself is kept unquoted
+		literal, _ := quote(str)
+		tok.Type, tok.Literal = lexer.String, literal
+		return &Value{String: &literal, Token: tok, unquoted: &str}, nil
+	}
+
+	f, ok := paramFloat(param)
+	if !ok {
+		return nil, lexer.ErrorAt(v.Token, "parameter %s must be a string or a number, got %T", name, param)
+	}
+	tok.Type, tok.Literal = lexer.Number, strconv.FormatFloat(f, 'f', -1, 64)
+	return &Value{Number: &f, Token: tok}, nil
+}
+
+func paramFloat(param any) (float64, bool) {
+	if n, ok := param.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	rv := reflect.ValueOf(param)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// Params returns the sorted names of the placeholders the program still
+// needs values for
+func (p *Program[T]) Params() []string {
+	if p.unbound == 0 {
+		return nil
+	}
+	return p.expr.Params()
+}
+
+func (p *Program[T]) unboundError() error {
+	return fmt.Errorf("unbound parameters %s", strings.Join(p.Params(), ", "))
+}
+
+// Bind returns a copy of the program with the placeholders replaced like
+// Expression.Bind does. Only the conditions with placeholders are compiled,
+// the rest of the program is shared, so a template compiled once can be
+// bound cheaply for every set of parameters.
+func (p *Program[T]) Bind(params map[string]any) (*Program[T], error) {
+	bound := make(map[*Condition[T]]*Condition[T])
+	expr, err := p.expr.bind(params, bound)
+	if err != nil {
+		return nil, err
+	}
+
+	b := *p
+	b.expr = expr
+	b.unbound = 0
+	b.conds = make([]*Condition[T], len(p.conds))
+	b.matchers = make([]Matcher[T], len(p.matchers))
+	if p.matchersE != nil {
+		b.matchersE = make([]MatcherE[T], len(p.matchersE))
+		copy(b.matchersE, p.matchersE)
+	}
+	b.index = make(map[*Condition[T]]int, len(p.index))
+	copy(b.matchers, p.matchers)
+	b.costs = make([]float64, len(p.costs))
+	copy(b.costs, p.costs)
+
+	for idx, cond := range p.conds {
+		if nc, found := bound[cond]; found {
+			m, mE, cost, err := b.matcher(nc)
+			if err != nil {
+				return nil, err
+			}
+			cond = nc
+			b.matchers[idx] = m
+			b.costs[idx] = cost
+			if b.matchersE != nil {
+				b.matchersE[idx] = mE
+			}
+		}
+		b.conds[idx] = cond
+		b.index[cond] = idx
+	}
+	return &b, nil
+}
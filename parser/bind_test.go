@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// thresholdCompiler compares the model to the number value of x < N and
+// x > N conditions
+func thresholdCompiler(c *Condition[uint]) (Matcher[uint], error) {
+	value, err := c.Value.GetFloatValue()
+	if err != nil {
+		return nil, err
+	}
+	switch c.Operator.Type {
+	case Less:
+		return func(model uint) bool { return float64(model) < value }, nil
+	case Greater:
+		return func(model uint) bool { return float64(model) > value }, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", c.Operator.Type)
+}
+
+func TestBind(t *testing.T) {
+	expr := parseString(t, `d < $radius and (arrival = :airport or alt > $radius)`)
+	if params := expr.Params(); !reflect.DeepEqual(params, []string{"airport", "radius"}) {
+		t.Errorf("got params %v, expected [airport radius]", params)
+	}
+	if result := formatExpr(t, expr, FormatOptions{}); result != `d < $radius and arrival = $airport or alt > $radius` {
+		t.Errorf("placeholders are formatted as %s", result)
+	}
+
+	bound, err := expr.Bind(map[string]any{"radius": 50, "airport": `UU"EE`, "unused": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := `d < 50 and arrival = "UU\"EE" or alt > 50`
+	if result := formatExpr(t, bound, FormatOptions{}); result != exp {
+		t.Errorf("got %s, expected %s", result, exp)
+	}
+	if tok := bound.Left.Condition.Value.Token; tok.Line != 1 || tok.Position != 5 {
+		t.Errorf("bound value must keep the placeholder position, got %s", tok)
+	}
+	if !expr.Left.Condition.Value.IsPlaceholder() {
+		t.Errorf("the original expression must not change")
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	expr := parseString(t, `a = 1 or b = $b`)
+	cases := []struct {
+		params map[string]any
+		err    string
+	}{
+		{map[string]any{}, "missing parameter b at line 1 pos 14"},
+		{map[string]any{"b": true}, "parameter b must be a string or a number, got bool at line 1 pos 14"},
+	}
+
+	for _, tc := range cases {
+		_, err := expr.Bind(tc.params)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("binding %v got %v, expected %s", tc.params, err, tc.err)
+		}
+	}
+}
+
+func TestBindBackslash(t *testing.T) {
+	expr := parseString(t, `path = $dir or path = $quoted`)
+	bound, err := expr.Bind(map[string]any{"dir": `C:\Temp\`, "quoted": `say "hi"\`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := []*Value{bound.Left.Condition.Value, bound.Right.Left.Condition.Value}
+	for i, exp := range []string{`C:\Temp\`, `say "hi"\`} {
+		if str, err := values[i].GetUnquotedStringValue(); err != nil || str != exp {
+			t.Errorf("got %s %v, expected %s", str, err, exp)
+		}
+	}
+
+	// the grammar has no literal for them
+	if _, err := bound.Format(FormatOptions{}); err == nil || err.Error() != "string value of path ends with a backslash and has no literal at line 1 pos 8" {
+		t.Errorf("got %v, expected the missing literal error", err)
+	}
+
+	data, err := json.Marshal(bound)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %v", err)
+	}
+	if !strings.Contains(string(data), `"string":"C:\\Temp\\"`) {
+		t.Errorf("bound value is lost in %s", data)
+	}
+
+	var decoded Expression[uint]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %v", err)
+	}
+	if str := decoded.Left.Condition.Value.MustGetUnquotedStringValue(); str != `C:\Temp\` {
+		t.Errorf("decoded %s, expected C:\\Temp\\", str)
+	}
+}
+
+func TestProgramBind(t *testing.T) {
+	compiled := 0
+	cb := func(c *Condition[uint]) (Matcher[uint], error) {
+		compiled++
+		return thresholdCompiler(c)
+	}
+
+	template, err := parseString(t, `x > 2 and x < $max`).Compile(cb)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if compiled != 1 {
+		t.Errorf("placeholder conditions must not be compiled, %d were", compiled)
+	}
+	if template.Evaluate(3) {
+		t.Errorf("unbound program must not match")
+	}
+	if _, err := template.EvaluateE(context.Background(), 3); err == nil || err.Error() != "unbound parameters max" {
+		t.Errorf("got %v, expected an unbound parameters error", err)
+	}
+
+	for max, expected := range map[int]bool{3: false, 5: true} {
+		p, err := template.Bind(map[string]any{"max": max})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.Params() != nil {
+			t.Errorf("bound program has params %v", p.Params())
+		}
+		if result := p.Evaluate(4); result != expected {
+			t.Errorf("with max %d got %v, expected %v", max, result, expected)
+		}
+		if !strings.Contains(p.Explain(4).String(), fmt.Sprintf("x < %d", max)) {
+			t.Errorf("explanation must show the bound value:\n%s", p.Explain(4))
+		}
+	}
+	if compiled != 3 {
+		t.Errorf("binding must only compile the placeholder conditions, %d compiled", compiled)
+	}
+	if template.Params()[0] != "max" {
+		t.Errorf("binding must not change the template")
+	}
+}
+
+func TestProgramBindE(t *testing.T) {
+	template, err := parseString(t, `c0 = $on or c4 = $on`).CompileE(bitmaskCompilerE)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := template.Bind(map[string]any{"on": 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.EvaluateE(context.Background(), 0); err == nil || !strings.Contains(err.Error(), "c4 = 1 at line 1 pos 13") {
+		t.Errorf("got %v, expected the c4 error", err)
+	}
+}
+
+func TestPlaceholderJSON(t *testing.T) {
+	expr := parseString(t, `arrival = :airport`)
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %v", err)
+	}
+	if !strings.Contains(string(data), `"placeholder":"airport"`) {
+		t.Errorf("placeholder is missing from %s", data)
+	}
+
+	var decoded Expression[uint]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %v", err)
+	}
+	if result := formatExpr(t, &decoded, FormatOptions{}); result != `arrival = $airport` {
+		t.Errorf("got %s, expected arrival = $airport", result)
+	}
+
+	invalid := `{"version": 2, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"placeholder": "1x"}}}}`
+	if err := json.Unmarshal([]byte(invalid), &decoded); err == nil {
+		t.Errorf("invalid placeholder name must fail")
+	}
+}
@@ -13,21 +13,29 @@ type (
 	}
 
 	Identifier struct {
-		Name  string
-		Token *lexer.Token
+		Name  string       `json:"name"`
+		Token *lexer.Token `json:"token,omitempty"`
 	}
 
+	// Value holds either a quoted string literal, a number or the name
+	// of a placeholder, without the sigil, which is bound to a string or
+	// a number before evaluation
 	Value struct {
-		String *string
-		Number *float64
-		Token  *lexer.Token
+		String      *string
+		Number      *float64
+		Placeholder *string
+		Token       *lexer.Token
+
+		// unquoted is the string a parameter was bound to, the literal
+		// only approximates strings the lexer can't read, i.e. the ones
+		// ending with a backslash
+		unquoted *string
 	}
 
 	Condition[T any] struct {
-		Identifier  *Identifier
-		Operator    *Operator
-		Value       *Value
-		MatcherFunc Matcher[T]
+		Identifier *Identifier
+		Operator   *Operator
+		Value      *Value
 	}
 )
 
@@ -43,6 +51,10 @@ func (v Value) IsFloat() bool {
 	return v.Number != nil
 }
 
+func (v Value) IsPlaceholder() bool {
+	return v.Placeholder != nil
+}
+
 func (v Value) GetStringValue() (string, error) {
 	if !v.IsString() {
 		return "", fmt.Errorf("token %s has no string value", v.Token.String())
@@ -57,6 +69,19 @@ func (v Value) GetFloatValue() (float64, error) {
 	return *v.Number, nil
 }
 
+// GetUnquotedStringValue returns the string value with the surrounding quotes
+// removed and escaped quotes resolved
+func (v Value) GetUnquotedStringValue() (string, error) {
+	if v.unquoted != nil {
+		return *v.unquoted, nil
+	}
+	str, err := v.GetStringValue()
+	if err != nil {
+		return "", err
+	}
+	return unquote(str)
+}
+
 func (v Value) MustGetStringValue() string {
 	str, err := v.GetStringValue()
 	if err != nil {
@@ -65,6 +90,14 @@ func (v Value) MustGetStringValue() string {
 	return str
 }
 
+func (v Value) MustGetUnquotedStringValue() string {
+	str, err := v.GetUnquotedStringValue()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
 func (v Value) MustGetFloatValue() float64 {
 	f, err := v.GetFloatValue()
 	if err != nil {
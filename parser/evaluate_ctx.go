@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+// CompileE builds a program from matchers which may fail. Evaluate still
+// works on such a program treating a failed condition as false, use
+// EvaluateE to get the errors.
+func (e *Expression[T]) CompileE(cb CompilationCallbackE[T]) (*Program[T], error) {
+	return e.compile(&Program[T]{cbE: cb})
+}
+
+func (p *Program[T]) conditionError(matcher uint32, err error) error {
+	cond := p.conds[matcher]
+	// the position goes before the wrapped error
+	at := lexer.ErrorAt(cond.Identifier.Token, "error evaluating %s", cond.Format())
+	return fmt.Errorf("%v: %w", at, err)
+}
+
+// EvaluateE runs the program against the model stopping at the first
+// failed condition or once the context is done. Programs compiled with
+// Compile can't fail and only check the context once before evaluation.
+func (p *Program[T]) EvaluateE(ctx context.Context, model T) (bool, error) {
+	if p == nil {
+		return false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if p.unbound > 0 {
+		return false, p.unboundError()
+	}
+	if p.matchersE == nil {
+		return p.Evaluate(model), nil
+	}
+
+	acc := false
+	code := p.code
+
+	for pc := 0; pc < len(code); {
+		ins := &code[pc]
+
+		switch ins.op {
+		case opJumpIfFalse:
+			if !acc {
+				pc = int(ins.target)
+			} else {
+				pc++
+			}
+			continue
+		case opJumpIfTrue:
+			if acc {
+				pc = int(ins.target)
+			} else {
+				pc++
+			}
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		ok, err := p.matchersE[ins.matcher](ctx, model)
+		if err != nil {
+			return false, p.conditionError(ins.matcher, err)
+		}
+		acc = ok
+
+		if (ins.op == opMatchJumpIfFalse && !acc) || (ins.op == opMatchJumpIfTrue && acc) {
+			pc = int(ins.target)
+		} else {
+			pc++
+		}
+	}
+
+	return acc, nil
+}
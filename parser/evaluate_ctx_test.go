@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+var errUnavailable = errors.New("field unavailable")
+
+// bitmaskCompilerE fails on conditions referring to bits above 3
+func bitmaskCompilerE(c *Condition[uint]) (MatcherE[uint], error) {
+	bit, err := strconv.Atoi(strings.TrimPrefix(c.Identifier.Name, "c"))
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, model uint) (bool, error) {
+		if bit > 3 {
+			return false, errUnavailable
+		}
+		return model&(1<<bit) != 0, nil
+	}, nil
+}
+
+func TestEvaluateE(t *testing.T) {
+	expr := parseString(t, `c0 = 1 or c1 = 1 and c4 = 1`)
+	p, err := expr.CompileE(bitmaskCompilerE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// c4 is never reached
+	ok, err := p.EvaluateE(context.Background(), 0b01)
+	if err != nil || !ok {
+		t.Errorf("unexpected result %v, %v", ok, err)
+	}
+
+	_, err = p.EvaluateE(context.Background(), 0b10)
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected unavailable field error, got %v", err)
+	}
+	exp := "error evaluating c4 = 1 at line 1 pos 22: field unavailable"
+	if err.Error() != exp {
+		t.Errorf("should throw %v, but throws %v", exp, err)
+	}
+
+	// the boolean path treats failed conditions as false
+	if p.Evaluate(0b10) {
+		t.Errorf("failed condition should evaluate to false")
+	}
+
+	// synthesized tokens, like the decoded ones, have no position
+	expr = parseString(t, `c4 = 1`)
+	expr.Left.Condition.Identifier.Token = &lexer.Token{Type: lexer.Identifier, Literal: "c4"}
+	if p, err = expr.CompileE(bitmaskCompilerE); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.EvaluateE(context.Background(), 0); err == nil || err.Error() != "error evaluating c4 = 1: field unavailable" {
+		t.Errorf("got %v, expected the error without a position", err)
+	}
+}
+
+func TestEvaluateECancelled(t *testing.T) {
+	expr := parseString(t, `c0 = 1`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.EvaluateE(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+
+	pe, err := expr.CompileE(bitmaskCompilerE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pe.EvaluateE(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
+
+func TestEvaluateEMatchesEvaluate(t *testing.T) {
+	expr := parseString(t, `(c0 = 1 or c1 = 1) and (c2 = 1 or c3 = 1)`)
+	p, err := expr.CompileE(bitmaskCompilerE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for model := uint(0); model < 16; model++ {
+		ok, err := p.EvaluateE(context.Background(), model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != p.Evaluate(model) {
+			t.Errorf("results differ for %04b", model)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// FieldFunc returns the value of the model field an identifier refers
+	// to, found is false if the identifier is unknown
+	FieldFunc[T any] func(model T, identifier string) (value any, found bool)
+
+	// Explanation mirrors the evaluated program: a condition or an and/or
+	// node with its operands in the order they were evaluated
+	Explanation[T any] struct {
+		// Condition is nil for and/or nodes
+		Condition *Condition[T]
+		Operator  CombineOperatorType
+		Operands  []*Explanation[T]
+
+		Result bool
+		// Skipped is set if the evaluation never reached the node because
+		// a previous operand short-circuited the chain
+		Skipped bool
+
+		// Value is the field value the condition was checked against,
+		// it's only set if the program has a FieldFunc and the condition
+		// wasn't skipped
+		Value    any
+		HasValue bool
+
+		// Unbound lists the parameters the program still needs, such
+		// a program never matches and nothing is evaluated
+		Unbound []string
+	}
+)
+
+// WithFields returns a copy of the program which reports field values
+// in explanations
+func (p *Program[T]) WithFields(fields FieldFunc[T]) *Program[T] {
+	c := *p
+	c.fields = fields
+	return &c
+}
+
+// Explain evaluates the model like Evaluate does, recording the result of
+// every condition and the conditions skipped by short-circuiting
+func (p *Program[T]) Explain(model T) *Explanation[T] {
+	if p == nil || p.root == nil {
+		return &Explanation[T]{}
+	}
+	if p.unbound > 0 {
+		return &Explanation[T]{Unbound: p.Params()}
+	}
+	return p.explain(p.root, model, false)
+}
+
+func (p *Program[T]) explain(n *progNode, model T, skip bool) *Explanation[T] {
+	if n.matcher >= 0 {
+		ex := &Explanation[T]{Condition: p.conds[n.matcher], Skipped: skip}
+		// skipped conditions never touched their fields
+		if !skip {
+			ex.Result = p.matchers[n.matcher](model)
+			if p.fields != nil {
+				ex.Value, ex.HasValue = p.fields(model, ex.Condition.Identifier.Name)
+			}
+		}
+		return ex
+	}
+
+	ex := &Explanation[T]{Operator: n.op, Skipped: skip}
+	done := skip
+	for _, child := range n.children {
+		operand := p.explain(child, model, done)
+		ex.Operands = append(ex.Operands, operand)
+		if done {
+			continue
+		}
+
+		ex.Result = operand.Result
+		if (n.op == And && !operand.Result) || (n.op == Or && operand.Result) {
+			done = true
+		}
+	}
+	if skip {
+		ex.Result = false
+	}
+	return ex
+}
+
+func (ex *Explanation[T]) status() string {
+	if ex.Skipped {
+		return "skipped"
+	}
+	if ex.Result {
+		return "true"
+	}
+	return "false"
+}
+
+// String renders the explanation as an indented tree, i.e.
+//
+//	false    and
+//	  true     callsign =~ "^AFL"  (callsign is "AFL123")
+//	  false    alt > 10000  (alt is 3500)
+//	  skipped  arrival = "UUEE"
+func (ex *Explanation[T]) String() string {
+	var sb strings.Builder
+	ex.render(&sb, "")
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (ex *Explanation[T]) render(sb *strings.Builder, indent string) {
+	sb.WriteString(fmt.Sprintf("%s%-8s ", indent, ex.status()))
+
+	if ex.Unbound != nil {
+		sb.WriteString("unbound parameters " + strings.Join(ex.Unbound, ", ") + "\n")
+		return
+	}
+	if ex.Condition == nil {
+		if ex.Operands == nil {
+			sb.WriteString("empty program\n")
+			return
+		}
+		sb.WriteString(combOperatorLiterals[ex.Operator] + "\n")
+		for _, operand := range ex.Operands {
+			operand.render(sb, indent+"  ")
+		}
+		return
+	}
+
+	sb.WriteString(ex.Condition.Format())
+	if ex.HasValue {
+		sb.WriteString(fmt.Sprintf("  (%s is %s)", ex.Condition.Identifier.Name, formatAny(ex.Value)))
+	}
+	sb.WriteString("\n")
+}
+
+func formatAny(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		if literal, ok := quote(val); ok {
+			return literal
+		}
+		return fmt.Sprintf("%q", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func bitmaskFields(model uint, identifier string) (any, bool) {
+	bit, err := strconv.Atoi(strings.TrimPrefix(identifier, "c"))
+	if err != nil {
+		return nil, false
+	}
+	return (model >> bit) & 1, true
+}
+
+func TestExplain(t *testing.T) {
+	expr := parseString(t, `c0 = 1 and (c1 = 1 or c2 = 1) and c3 = 1`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ex := p.WithFields(bitmaskFields).Explain(0b0101)
+	if ex.Result != p.Evaluate(0b0101) {
+		t.Errorf("explanation result differs from evaluation")
+	}
+
+	exp := strings.Join([]string{
+		`false    and`,
+		`  true     c0 = 1  (c0 is 1)`,
+		`  true     or`,
+		`    false    c1 = 1  (c1 is 0)`,
+		`    true     c2 = 1  (c2 is 1)`,
+		`  false    c3 = 1  (c3 is 0)`,
+	}, "\n")
+	if ex.String() != exp {
+		t.Errorf("invalid explanation, got\n%s\nexpected\n%s", ex, exp)
+	}
+
+	ex = p.WithFields(bitmaskFields).Explain(0b0010)
+	exp = strings.Join([]string{
+		`false    and`,
+		`  false    c0 = 1  (c0 is 0)`,
+		`  skipped  or`,
+		`    skipped  c1 = 1`,
+		`    skipped  c2 = 1`,
+		`  skipped  c3 = 1`,
+	}, "\n")
+	if ex.String() != exp {
+		t.Errorf("invalid explanation, got\n%s\nexpected\n%s", ex, exp)
+	}
+}
+
+func TestExplainMatchesEvaluate(t *testing.T) {
+	expr := parseString(t, `c0 = 1 or c1 = 1 and (c2 = 1 or c3 = 1)`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for model := uint(0); model < 16; model++ {
+		if p.Explain(model).Result != p.Evaluate(model) {
+			t.Errorf("explanation result differs from evaluation for %04b", model)
+		}
+	}
+}
+
+func TestExplainUnbound(t *testing.T) {
+	expr := parseString(t, `c0 = $x or c1 = 1`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ex := p.WithFields(bitmaskFields).Explain(0b0010)
+	if ex.Result || ex.Result != p.Evaluate(0b0010) {
+		t.Errorf("explanation result differs from evaluation")
+	}
+	if exp := "false    unbound parameters x"; ex.String() != exp {
+		t.Errorf("invalid explanation, got\n%s\nexpected\n%s", ex, exp)
+	}
+
+	bound, err := p.Bind(map[string]any{"x": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex := bound.Explain(0b0010); !ex.Result || ex.Unbound != nil {
+		t.Errorf("bound program must be explained, got\n%s", ex)
+	}
+}
@@ -1,10 +1,17 @@
 package parser
 
-import "github.com/vatsimnerd/lee/lexer"
+import (
+	"context"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
 
 type (
+	// Grouping is a parenthesized expression or the expansion of a macro
+	// reference, Reference is nil for the former
 	Grouping[T any] struct {
 		Expression *Expression[T]
+		Reference  *Reference
 	}
 
 	LeftExpression[T any] struct {
@@ -25,9 +32,20 @@ type (
 
 	Matcher[T any]             func(model T) bool
 	CompilationCallback[T any] func(c *Condition[T]) (Matcher[T], error)
+
+	// MatcherE is a matcher which may fail, i.e. when a field is unavailable
+	MatcherE[T any]             func(ctx context.Context, model T) (bool, error)
+	CompilationCallbackE[T any] func(c *Condition[T]) (MatcherE[T], error)
+
+	// CostedCompilationCallback also estimates how expensive the matcher
+	// is to evaluate, relative to a plain comparison which costs 1
+	CostedCompilationCallback[T any] func(c *Condition[T]) (Matcher[T], float64, error)
 )
 
 func (g *Grouping[T]) String() string {
+	if g.Reference != nil {
+		return "@" + g.Reference.Name + "(" + g.Expression.String() + ")"
+	}
 	return "(" + g.Expression.String() + ")"
 }
 
@@ -51,52 +69,3 @@ func (le *LeftExpression[T]) String() string {
 func (co *CombineOperator) String() string {
 	return co.Type.String()
 }
-
-func (e *Expression[T]) Compile(cb CompilationCallback[T]) error {
-	var err error
-
-	if e.Left.Condition != nil {
-		e.Left.Condition.MatcherFunc, err = cb(e.Left.Condition)
-		if err != nil {
-			return err
-		}
-	} else {
-		err = e.Left.Grouping.Expression.Compile(cb)
-		if err != nil {
-			return err
-		}
-	}
-
-	if e.Right != nil {
-		return e.Right.Compile(cb)
-	}
-
-	return nil
-}
-
-func (e *Expression[T]) Evaluate(model T) bool {
-	var left bool
-
-	if e.Left.Condition != nil {
-		left = e.Left.Condition.MatcherFunc(model)
-	} else {
-		left = e.Left.Grouping.Expression.Evaluate(model)
-	}
-
-	if e.Right == nil {
-		return left
-	}
-
-	switch e.Operator.Type {
-	case And:
-		if !left {
-			return false
-		}
-	case Or:
-		if left {
-			return true
-		}
-	}
-
-	return e.Right.Evaluate(model)
-}
@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+type (
+	// FilterOptions configure the batch filtering methods of a program
+	FilterOptions struct {
+		// Workers is the number of goroutines evaluating models,
+		// GOMAXPROCS if zero
+		Workers int
+		// Unordered lets the results come in any order, which saves the
+		// reordering buffer when streaming
+		Unordered bool
+	}
+
+	streamItem[T any] struct {
+		seq   uint64
+		model T
+		ok    bool
+	}
+)
+
+const (
+	// slices shorter than this are filtered without spawning goroutines
+	minParallelLen = 256
+	// chunks per worker when filtering slices, more chunks balance the
+	// load better when some models are more expensive than others
+	chunksPerWorker = 4
+	// in-flight models per worker when streaming in order
+	streamWindow = 16
+)
+
+// WithFilterOptions returns a copy of the program using the options
+// for Filter, FilterIndices and FilterStream
+func (p *Program[T]) WithFilterOptions(opts FilterOptions) *Program[T] {
+	c := *p
+	c.filterOpts = opts
+	return &c
+}
+
+// options are the zero ones for a nil program, which never matches like
+// it doesn't in Evaluate
+func (p *Program[T]) options() FilterOptions {
+	if p == nil {
+		return FilterOptions{}
+	}
+	return p.filterOpts
+}
+
+func (p *Program[T]) workers() int {
+	if workers := p.options().Workers; workers > 0 {
+		return workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Filter returns the models matching the program
+func (p *Program[T]) Filter(models []T) []T {
+	indices := p.FilterIndices(models)
+	result := make([]T, len(indices))
+	for i, idx := range indices {
+		result[i] = models[idx]
+	}
+	return result
+}
+
+// FilterIndices returns the indices of the models matching the program
+func (p *Program[T]) FilterIndices(models []T) []int {
+	workers := p.workers()
+	if workers == 1 || len(models) < minParallelLen {
+		return p.filterRange(models, 0, len(models), nil)
+	}
+
+	chunks := workers * chunksPerWorker
+	chunkLen := (len(models) + chunks - 1) / chunks
+	chunks = (len(models) + chunkLen - 1) / chunkLen
+
+	results := make([][]int, chunks)
+	next := make(chan int, chunks)
+	for i := 0; i < chunks; i++ {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range next {
+				start := chunk * chunkLen
+				end := start + chunkLen
+				if end > len(models) {
+					end = len(models)
+				}
+				results[chunk] = p.filterRange(models, start, end, nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// chunks are merged in order anyway, it's as cheap as merging them
+	// in the order they complete
+	total := 0
+	for _, r := range results {
+		total += len(r)
+	}
+	indices := make([]int, 0, total)
+	for _, r := range results {
+		indices = append(indices, r...)
+	}
+	return indices
+}
+
+func (p *Program[T]) filterRange(models []T, start int, end int, indices []int) []int {
+	for i := start; i < end; i++ {
+		if p.Evaluate(models[i]) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// FilterStream evaluates the models read from in and sends the matching
+// ones to the returned channel, which is closed once in is closed and
+// drained or the context is done
+func (p *Program[T]) FilterStream(ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	workers := p.workers()
+
+	if p.options().Unordered {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.streamUnordered(ctx, in, out)
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+		return out
+	}
+
+	go p.streamOrdered(ctx, in, out, workers)
+	return out
+}
+
+func (p *Program[T]) streamUnordered(ctx context.Context, in <-chan T, out chan<- T) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case model, ok := <-in:
+			if !ok {
+				return
+			}
+			if !p.Evaluate(model) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- model:
+			}
+		}
+	}
+}
+
+// streamOrdered numbers the incoming models, evaluates them in parallel
+// and sends the matching ones in the original order. The number of models
+// in flight is limited so a slow model can't make the reordering buffer
+// grow without bounds.
+func (p *Program[T]) streamOrdered(ctx context.Context, in <-chan T, out chan<- T, workers int) {
+	defer close(out)
+
+	jobs := make(chan streamItem[T], workers)
+	results := make(chan streamItem[T], workers)
+	window := make(chan struct{}, workers*streamWindow)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				item.ok = p.Evaluate(item.model)
+				select {
+				case <-ctx.Done():
+				case results <- item:
+				}
+			}
+		}()
+	}
+
+	// dispatcher
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(results)
+		}()
+
+		var seq uint64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case window <- struct{}{}:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case model, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case jobs <- streamItem[T]{seq: seq, model: model}:
+				}
+				seq++
+			}
+		}
+	}()
+
+	pending := make(map[uint64]streamItem[T])
+	var next uint64
+	for item := range results {
+		pending[item.seq] = item
+		for {
+			ready, found := pending[next]
+			if !found {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-window
+
+			if !ready.ok {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case out <- ready.model:
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func filterProgram(t *testing.T) *Program[uint] {
+	expr := parseString(t, `c0 = 1 and (c1 = 1 or c2 = 1)`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func filterModels(n int) ([]uint, []int) {
+	models := make([]uint, n)
+	var expected []int
+	for i := range models {
+		models[i] = uint(i % 8)
+		if models[i]&1 != 0 && models[i]&6 != 0 {
+			expected = append(expected, i)
+		}
+	}
+	return models, expected
+}
+
+func TestFilterIndices(t *testing.T) {
+	p := filterProgram(t)
+
+	for _, n := range []int{0, 10, 1000, 10007} {
+		models, expected := filterModels(n)
+		for _, workers := range []int{1, 3, 8} {
+			indices := p.WithFilterOptions(FilterOptions{Workers: workers}).FilterIndices(models)
+			if len(indices) != len(expected) || (len(expected) > 0 && !reflect.DeepEqual(indices, expected)) {
+				t.Errorf("invalid indices for %d models and %d workers", n, workers)
+			}
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	p := filterProgram(t)
+	result := p.Filter([]uint{0b001, 0b011, 0b110, 0b101, 0b111})
+	if !reflect.DeepEqual(result, []uint{0b011, 0b101, 0b111}) {
+		t.Errorf("invalid result %v", result)
+	}
+}
+
+func TestFilterNil(t *testing.T) {
+	var p *Program[uint]
+	models, _ := filterModels(1000)
+	if result := p.Filter(models); len(result) != 0 {
+		t.Errorf("nil program must match nothing, got %v", result)
+	}
+	if indices := p.FilterIndices(models); len(indices) != 0 {
+		t.Errorf("nil program must match nothing, got %v", indices)
+	}
+	for range p.FilterStream(context.Background(), streamModels(models)) {
+		t.Errorf("nil program must stream nothing")
+	}
+}
+
+func streamModels(models []uint) <-chan uint {
+	in := make(chan uint)
+	go func() {
+		defer close(in)
+		for _, m := range models {
+			in <- m
+		}
+	}()
+	return in
+}
+
+func TestFilterStream(t *testing.T) {
+	p := filterProgram(t)
+	models, expected := filterModels(5000)
+
+	var ordered []uint
+	for m := range p.WithFilterOptions(FilterOptions{Workers: 4}).FilterStream(context.Background(), streamModels(models)) {
+		ordered = append(ordered, m)
+	}
+	if len(ordered) != len(expected) {
+		t.Fatalf("invalid number of models, got %d, expected %d", len(ordered), len(expected))
+	}
+	for i, idx := range expected {
+		if ordered[i] != models[idx] {
+			t.Fatalf("model %d is out of order", i)
+		}
+	}
+
+	var unordered []uint
+	opts := FilterOptions{Workers: 4, Unordered: true}
+	for m := range p.WithFilterOptions(opts).FilterStream(context.Background(), streamModels(models)) {
+		unordered = append(unordered, m)
+	}
+	sort.Slice(unordered, func(i, j int) bool { return unordered[i] < unordered[j] })
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i] < ordered[j] })
+	if !reflect.DeepEqual(unordered, ordered) {
+		t.Errorf("unordered stream returns different models")
+	}
+}
+
+func TestFilterStreamCancel(t *testing.T) {
+	p := filterProgram(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the input is never closed, cancelling must close the output anyway
+	in := make(chan uint)
+	go func() {
+		for {
+			select {
+			case in <- 1:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for _, unordered := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(ctx)
+		out := p.WithFilterOptions(FilterOptions{Unordered: unordered}).FilterStream(ctx, in)
+		cancel()
+		for range out {
+		}
+	}
+	cancel()
+}
+
+func BenchmarkFilterSequential(b *testing.B) {
+	p := benchmarkProgram(b).WithFilterOptions(FilterOptions{Workers: 1})
+	models := benchmarkModels()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Filter(models)
+	}
+}
+
+func BenchmarkFilterParallel(b *testing.B) {
+	p := benchmarkProgram(b)
+	models := benchmarkModels()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Filter(models)
+	}
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+type (
+	// FormatOptions control the canonical printer
+	FormatOptions struct {
+		// MaxWidth is the line width after which expressions are wrapped
+		// at combine operators, zero disables wrapping
+		MaxWidth int
+		// Indent is used for the contents of wrapped groupings,
+		// four spaces if empty
+		Indent string
+		// KeepReferences prints expanded macros as their @name references
+		// instead of their contents
+		KeepReferences bool
+		// Macros expand the references of the source given to Format.
+		// Without them references are errors unless they're kept, kept
+		// references needn't be defined.
+		Macros *Macros
+	}
+
+	// fmtOperand is a single element of a flattened expression chain,
+	// either a condition, a parenthesized sub-chain or a macro reference
+	fmtOperand[T any] struct {
+		condition *Condition[T]
+		group     *fmtChain[T]
+		reference *Reference
+	}
+
+	// fmtChain is an expression flattened into a list of operands joined
+	// by operators, i.e. "a and b or c" is {[a b c] [and or]}
+	fmtChain[T any] struct {
+		operands  []fmtOperand[T]
+		operators []CombineOperatorType
+	}
+)
+
+// Format prints the expression as valid canonical filter source.
+//
+// Operators are spelled in lower case, strings are double quoted, numbers
+// are printed in their shortest form and placeholders with the $ sigil.
+// Both combine operators share the same precedence and group to the
+// right, so "a and b or c" means "a and (b or c)". Parentheses are only
+// kept where dropping them would change the meaning of the expression.
+//
+// A string ending with a backslash has no literal, the backslash would
+// escape the closing quote. Only bound parameters and decoded JSON can
+// hold one, formatting it is an error.
+func (e *Expression[T]) Format(opts FormatOptions) (string, error) {
+	if opts.Indent == "" {
+		opts.Indent = "    "
+	}
+	chain := newFmtChain(e, opts.KeepReferences)
+	if err := chain.check(); err != nil {
+		return "", err
+	}
+	return chain.format(opts, ""), nil
+}
+
+// Format parses the filter source and prints it in the canonical form
+func Format(src string, opts FormatOptions) (string, error) {
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		return "", err
+	}
+
+	p := newParser[any](tokens)
+	p.macros = opts.Macros
+	p.syntaxOnly = opts.Macros == nil && opts.KeepReferences
+	expr, err := p.parseAll()
+	if err != nil {
+		return "", err
+	}
+
+	return expr.Format(opts)
+}
+
+func newFmtChain[T any](e *Expression[T], keepRefs bool) *fmtChain[T] {
+	chain := &fmtChain[T]{}
+
+	for ; e != nil; e = e.Right {
+		var op *CombineOperatorType
+		if e.Operator != nil && e.Right != nil {
+			op = &e.Operator.Type
+		}
+
+		if e.Left.Condition != nil {
+			chain.operands = append(chain.operands, fmtOperand[T]{condition: e.Left.Condition})
+		} else if g := e.Left.Grouping; keepRefs && g.Reference != nil {
+			chain.operands = append(chain.operands, fmtOperand[T]{reference: g.Reference})
+		} else {
+			inner := newFmtChain(e.Left.Grouping.Expression, keepRefs)
+			if inner.canSplice(op) {
+				chain.operands = append(chain.operands, inner.operands...)
+				chain.operators = append(chain.operators, inner.operators...)
+			} else {
+				chain.operands = append(chain.operands, fmtOperand[T]{group: inner})
+			}
+		}
+
+		if op != nil {
+			chain.operators = append(chain.operators, *op)
+		}
+	}
+
+	return chain
+}
+
+// canSplice reports whether the chain may be inlined into the outer chain
+// without parentheses given the operator following it, nil means the chain
+// is the last operand of the outer one
+func (c *fmtChain[T]) canSplice(next *CombineOperatorType) bool {
+	if next == nil || len(c.operators) == 0 {
+		return true
+	}
+	for _, op := range c.operators {
+		if op != *next {
+			return false
+		}
+	}
+	return true
+}
+
+// check reports the first printed value the grammar can't express
+func (c *fmtChain[T]) check() error {
+	for _, operand := range c.operands {
+		if operand.group != nil {
+			if err := operand.group.check(); err != nil {
+				return err
+			}
+			continue
+		}
+		if cond := operand.condition; cond != nil {
+			if _, ok := valueLiteral(cond.Value); !ok {
+				return lexer.ErrorAt(cond.Value.Token, "string value of %s ends with a backslash and has no literal", cond.Identifier.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *fmtChain[T]) inline() string {
+	var sb strings.Builder
+	for i, operand := range c.operands {
+		if i > 0 {
+			sb.WriteString(" " + combOperatorLiterals[c.operators[i-1]] + " ")
+		}
+		sb.WriteString(operand.inline())
+	}
+	return sb.String()
+}
+
+func (c *fmtChain[T]) format(opts FormatOptions, indent string) string {
+	line := c.inline()
+	if opts.MaxWidth <= 0 || len(indent)+len(line) <= opts.MaxWidth || len(c.operands) == 1 {
+		return line
+	}
+
+	var sb strings.Builder
+	for i, operand := range c.operands {
+		prefix := ""
+		if i > 0 {
+			sb.WriteString("\n" + indent)
+			prefix = combOperatorLiterals[c.operators[i-1]] + " "
+		}
+		sb.WriteString(prefix)
+
+		if operand.group == nil {
+			sb.WriteString(operand.inline())
+			continue
+		}
+
+		groupLine := operand.inline()
+		if len(indent)+len(prefix)+len(groupLine) <= opts.MaxWidth {
+			sb.WriteString(groupLine)
+			continue
+		}
+
+		inner := indent + opts.Indent
+		sb.WriteString("(\n" + inner)
+		sb.WriteString(operand.group.format(opts, inner))
+		sb.WriteString("\n" + indent + ")")
+	}
+	return sb.String()
+}
+
+func (o fmtOperand[T]) inline() string {
+	if o.condition != nil {
+		return formatCondition(o.condition)
+	}
+	if o.reference != nil {
+		return "@" + o.reference.Name
+	}
+	return "(" + o.group.inline() + ")"
+}
+
+// Format prints the condition in the canonical form. A string value
+// ending with a backslash is only approximated, see Expression.Format.
+func (c *Condition[T]) Format() string {
+	return formatCondition(c)
+}
+
+func formatCondition[T any](c *Condition[T]) string {
+	return c.Identifier.Name + " " + operatorLiterals[c.Operator.Type] + " " + formatValue(c.Value)
+}
+
+func formatValue(v *Value) string {
+	literal, _ := valueLiteral(v)
+	return literal
+}
+
+// valueLiteral returns the canonical literal of the value, ok is false if
+// the literal only approximates a string ending with a backslash
+func valueLiteral(v *Value) (literal string, ok bool) {
+	if v.IsFloat() {
+		return strconv.FormatFloat(*v.Number, 'f', -1, 64), true
+	}
+	if v.IsPlaceholder() {
+		return "$" + *v.Placeholder, true
+	}
+
+	if str, err := v.GetUnquotedStringValue(); err == nil {
+		return quote(str)
+	}
+	return *v.String, true
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+type formatCase struct {
+	input  string
+	output string
+}
+
+var formatCases = []formatCase{
+	{`a=1`, `a = 1`},
+	{`a = 1.50 AND b != 'x'`, `a = 1.5 and b != "x"`},
+	{`a =~ "^AFL" && b !~ 'x"y' || c <= 3`, `a =~ "^AFL" and b !~ "x\"y" or c <= 3`},
+	{`((a = 1))`, `a = 1`},
+	{`a = 1 and (b = 2 or c = 3)`, `a = 1 and b = 2 or c = 3`},
+	{`(a = 1 and b = 2) and c = 3`, `a = 1 and b = 2 and c = 3`},
+	{`(a = 1 or b = 2) and c = 3`, `(a = 1 or b = 2) and c = 3`},
+	{`(a = 1 and (b = 2 or c = 3)) or d = 4`, `(a = 1 and b = 2 or c = 3) or d = 4`},
+	{`a = 'it\'s'`, `a = "it's"`},
+	{`lon>-1.50 and lat < -0`, `lon > -1.5 and lat < -0`},
+	{`flight_plan.arrival="EGLL" AND legs.0.fix!='x'`, `flight_plan.arrival = "EGLL" and legs.0.fix != "x"`},
+}
+
+func parseString(t *testing.T, src string) *Expression[uint] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := Parse[uint](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	return expr
+}
+
+func TestFormat(t *testing.T) {
+	for i, tc := range formatCases {
+		result, err := Format(tc.input, FormatOptions{})
+		if err != nil {
+			t.Errorf("error formatting case %d: %v", i+1, err)
+			continue
+		}
+		if result != tc.output {
+			t.Errorf("invalid format in case %d, got %s, expected %s", i+1, result, tc.output)
+		}
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	cases := map[string]string{
+		`callsign = "A") or alt > 5`: "unexpected token ) at line 1 pos 15",
+		`a = 1 and (b = 2))`:         "unexpected token ) at line 1 pos 18",
+		`a = 1 b = 2`:                "unexpected token b at line 1 pos 7",
+		`a = 1 and`:                  "unexpected token  at line 1 pos 10",
+	}
+	for src, exp := range cases {
+		if _, err := Format(src, FormatOptions{}); err == nil || err.Error() != exp {
+			t.Errorf("%s got %v, expected %s", src, err, exp)
+		}
+	}
+}
+
+func TestFormatWrap(t *testing.T) {
+	src := `callsign =~ "^AFL" and (arrival = "UUEE" or arrival = "UUDD") and altitude > 10000`
+	exp := strings.Join([]string{
+		`callsign =~ "^AFL"`,
+		`and (`,
+		`  arrival = "UUEE"`,
+		`  or arrival = "UUDD"`,
+		`)`,
+		`and altitude > 10000`,
+	}, "\n")
+
+	result, err := Format(src, FormatOptions{MaxWidth: 30, Indent: "  "})
+	if err != nil {
+		t.Fatalf("unexpected error formatting: %v", err)
+	}
+	if result != exp {
+		t.Errorf("invalid wrapped format, got\n%s\nexpected\n%s", result, exp)
+	}
+
+	// the wrapped output must be parsed back to the same expression
+	again, err := Format(result, FormatOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error formatting wrapped output: %v", err)
+	}
+	flat, _ := Format(src, FormatOptions{})
+	if again != flat {
+		t.Errorf("wrapped output parses differently, got %s, expected %s", again, flat)
+	}
+}
+
+// randomExpression generates source using conditions c0..c(n-1) which
+// the bitmask compiler below evaluates to the corresponding model bit
+func randomExpression(r *rand.Rand, n int, depth int) string {
+	var sb strings.Builder
+	operands := r.Intn(3) + 1
+	for i := 0; i < operands; i++ {
+		if i > 0 {
+			sb.WriteString([]string{" and ", " or ", " && ", " || "}[r.Intn(4)])
+		}
+		if depth > 0 && r.Intn(3) == 0 {
+			sb.WriteString("(" + randomExpression(r, n, depth-1) + ")")
+		} else {
+			sb.WriteString("c" + strconv.Itoa(r.Intn(n)) + " = 1")
+		}
+	}
+	return sb.String()
+}
+
+func bitmaskCompiler(c *Condition[uint]) (Matcher[uint], error) {
+	bit, err := strconv.Atoi(strings.TrimPrefix(c.Identifier.Name, "c"))
+	if err != nil {
+		return nil, err
+	}
+	return func(model uint) bool { return model&(1<<bit) != 0 }, nil
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	const vars = 4
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		src := randomExpression(r, vars, 3)
+		orig := parseString(t, src)
+
+		formatted := formatExpr(t, orig, FormatOptions{MaxWidth: r.Intn(40)})
+		parsed := parseString(t, formatted)
+
+		if again := formatExpr(t, parsed, FormatOptions{}); again != formatExpr(t, orig, FormatOptions{}) {
+			t.Fatalf("format is not stable for %s: %s vs %s", src, again, formatted)
+		}
+
+		origProgram, err := orig.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", src, err)
+		}
+		parsedProgram, err := parsed.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", formatted, err)
+		}
+
+		for model := uint(0); model < 1<<vars; model++ {
+			if origProgram.Evaluate(model) != parsedProgram.Evaluate(model) {
+				t.Fatalf(
+					"%s and its formatted form %s evaluate differently for %04b",
+					src,
+					formatted,
+					model,
+				)
+			}
+		}
+	}
+}
+
+func formatExpr[T any](t *testing.T, e *Expression[T], opts FormatOptions) string {
+	t.Helper()
+	result, err := e.Format(opts)
+	if err != nil {
+		t.Fatalf("error formatting %s: %v", e, err)
+	}
+	return result
+}
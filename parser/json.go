@@ -0,0 +1,356 @@
+package parser
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+// JSONVersion is the version of the JSON encoding written by
+// Expression.MarshalJSON. Version 2 added placeholders and references,
+// version 1 documents without them are still read, other versions are
+// rejected.
+const JSONVersion = 2
+
+// JSONSchema describes the JSON encoding of an expression document
+//
+//go:embed schema.json
+var JSONSchema string
+
+type (
+	jsonExpression[T any] struct {
+		Version  int                `json:"version,omitempty"`
+		Left     *jsonOperand[T]    `json:"left"`
+		Operator *CombineOperator   `json:"operator,omitempty"`
+		Right    *jsonExpression[T] `json:"right,omitempty"`
+	}
+
+	jsonOperand[T any] struct {
+		Condition *Condition[T]    `json:"condition,omitempty"`
+		Grouping  *jsonGrouping[T] `json:"grouping,omitempty"`
+	}
+
+	jsonGrouping[T any] struct {
+		Expression *jsonExpression[T] `json:"expression"`
+		Reference  *Reference         `json:"reference,omitempty"`
+	}
+
+	jsonCondition struct {
+		Identifier *Identifier `json:"identifier"`
+		Operator   *Operator   `json:"operator"`
+		Value      *Value      `json:"value"`
+	}
+
+	jsonOperator struct {
+		Symbol string       `json:"symbol"`
+		Token  *lexer.Token `json:"token,omitempty"`
+	}
+
+	jsonValue struct {
+		String      *string      `json:"string,omitempty"`
+		Number      *float64     `json:"number,omitempty"`
+		Placeholder *string      `json:"placeholder,omitempty"`
+		Token       *lexer.Token `json:"token,omitempty"`
+	}
+)
+
+func toJSONExpression[T any](e *Expression[T]) *jsonExpression[T] {
+	je := &jsonExpression[T]{Left: &jsonOperand[T]{}}
+	if e.Left.Condition != nil {
+		je.Left.Condition = e.Left.Condition
+	} else {
+		g := e.Left.Grouping
+		je.Left.Grouping = &jsonGrouping[T]{toJSONExpression(g.Expression), g.Reference}
+	}
+	if e.Right != nil {
+		je.Operator = e.Operator
+		je.Right = toJSONExpression(e.Right)
+	}
+	return je
+}
+
+func fromJSONExpression[T any](je *jsonExpression[T]) (*Expression[T], error) {
+	if je == nil {
+		return nil, fmt.Errorf("expression is missing")
+	}
+	if je.Left == nil {
+		return nil, fmt.Errorf("expression has no left operand")
+	}
+
+	e := &Expression[T]{Left: &LeftExpression[T]{}}
+	if je.Left.Condition != nil && je.Left.Grouping != nil {
+		return nil, fmt.Errorf("left operand has both condition and grouping")
+	} else if je.Left.Condition != nil {
+		e.Left.Condition = je.Left.Condition
+	} else if je.Left.Grouping != nil {
+		inner, err := fromJSONExpression(je.Left.Grouping.Expression)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := checkReference(je.Left.Grouping.Reference)
+		if err != nil {
+			return nil, err
+		}
+		e.Left.Grouping = &Grouping[T]{Expression: inner, Reference: ref}
+	} else {
+		return nil, fmt.Errorf("left operand has neither condition nor grouping")
+	}
+
+	if (je.Operator == nil) != (je.Right == nil) {
+		return nil, fmt.Errorf("operator and right operand must be set together")
+	}
+	if je.Right != nil {
+		right, err := fromJSONExpression(je.Right)
+		if err != nil {
+			return nil, err
+		}
+		e.Operator = je.Operator
+		e.Right = right
+	}
+	return e, nil
+}
+
+// MarshalJSON encodes the expression as a versioned document
+// described by JSONSchema
+func (e *Expression[T]) MarshalJSON() ([]byte, error) {
+	je := toJSONExpression(e)
+	je.Version = JSONVersion
+	return json.Marshal(je)
+}
+
+// UnmarshalJSON decodes a document produced by MarshalJSON. Tokens are
+// optional, the missing ones are synthesized with zero line and position.
+func (e *Expression[T]) UnmarshalJSON(data []byte) error {
+	var je jsonExpression[T]
+	if err := json.Unmarshal(data, &je); err != nil {
+		return err
+	}
+	if je.Version < 1 || je.Version > JSONVersion {
+		return fmt.Errorf("unsupported expression version %d, expected %d", je.Version, JSONVersion)
+	}
+
+	decoded, err := fromJSONExpression(&je)
+	if err != nil {
+		return err
+	}
+	if je.Version < 2 && decoded.hasVersion2() {
+		return fmt.Errorf("placeholders and references require expression version 2, got %d", je.Version)
+	}
+	*e = *decoded
+	return nil
+}
+
+// hasVersion2 reports whether the expression uses placeholders or
+// references which version 1 documents can't have
+func (e *Expression[T]) hasVersion2() bool {
+	if c := e.Left.Condition; c != nil {
+		if c.Value.IsPlaceholder() {
+			return true
+		}
+	} else if e.Left.Grouping.Reference != nil || e.Left.Grouping.Expression.hasVersion2() {
+		return true
+	}
+	return e.Right != nil && e.Right.hasVersion2()
+}
+
+func (g *Grouping[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&jsonGrouping[T]{toJSONExpression(g.Expression), g.Reference})
+}
+
+func (g *Grouping[T]) UnmarshalJSON(data []byte) error {
+	var jg jsonGrouping[T]
+	if err := json.Unmarshal(data, &jg); err != nil {
+		return err
+	}
+
+	expr, err := fromJSONExpression(jg.Expression)
+	if err != nil {
+		return err
+	}
+	ref, err := checkReference(jg.Reference)
+	if err != nil {
+		return err
+	}
+	g.Expression = expr
+	g.Reference = ref
+	return nil
+}
+
+func (c *Condition[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&jsonCondition{c.Identifier, c.Operator, c.Value})
+}
+
+func (c *Condition[T]) UnmarshalJSON(data []byte) error {
+	var jc jsonCondition
+	if err := json.Unmarshal(data, &jc); err != nil {
+		return err
+	}
+	if jc.Identifier == nil || jc.Identifier.Name == "" {
+		return fmt.Errorf("condition has no identifier")
+	}
+	if !lexer.Is(lexer.Identifier, jc.Identifier.Name) {
+		return fmt.Errorf("invalid identifier name %q", jc.Identifier.Name)
+	}
+	if jc.Operator == nil {
+		return fmt.Errorf("condition has no operator")
+	}
+	if jc.Value == nil {
+		return fmt.Errorf("condition has no value")
+	}
+
+	if jc.Identifier.Token == nil {
+		jc.Identifier.Token = &lexer.Token{Type: lexer.Identifier, Literal: jc.Identifier.Name}
+	}
+
+	c.Identifier = jc.Identifier
+	c.Operator = jc.Operator
+	c.Value = jc.Value
+	return nil
+}
+
+func (o *Operator) MarshalJSON() ([]byte, error) {
+	symbol, found := operatorLiterals[o.Type]
+	if !found {
+		return nil, fmt.Errorf("invalid operator type %s", o.Type)
+	}
+	return json.Marshal(&jsonOperator{symbol, o.Token})
+}
+
+func (o *Operator) UnmarshalJSON(data []byte) error {
+	var jo jsonOperator
+	if err := json.Unmarshal(data, &jo); err != nil {
+		return err
+	}
+
+	for tokenType, opType := range operators {
+		if operatorLiterals[opType] == jo.Symbol {
+			o.Type = opType
+			o.Token = jo.Token
+			if o.Token == nil {
+				o.Token = &lexer.Token{Type: tokenType, Literal: jo.Symbol}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown operator %q", jo.Symbol)
+}
+
+func (co *CombineOperator) MarshalJSON() ([]byte, error) {
+	symbol, found := combOperatorLiterals[co.Type]
+	if !found {
+		return nil, fmt.Errorf("invalid combine operator type %s", co.Type)
+	}
+	return json.Marshal(&jsonOperator{symbol, co.Token})
+}
+
+func (co *CombineOperator) UnmarshalJSON(data []byte) error {
+	var jo jsonOperator
+	if err := json.Unmarshal(data, &jo); err != nil {
+		return err
+	}
+
+	for tokenType, opType := range combOperators {
+		if combOperatorLiterals[opType] == jo.Symbol {
+			co.Type = opType
+			co.Token = jo.Token
+			if co.Token == nil {
+				co.Token = &lexer.Token{Type: tokenType, Literal: jo.Symbol}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown combine operator %q", jo.Symbol)
+}
+
+// MarshalJSON encodes string values without the quotes
+func (v *Value) MarshalJSON() ([]byte, error) {
+	jv := jsonValue{Number: v.Number, Placeholder: v.Placeholder, Token: v.Token}
+	if v.IsString() {
+		str, err := v.GetUnquotedStringValue()
+		if err != nil {
+			return nil, err
+		}
+		jv.String = &str
+	}
+	return json.Marshal(&jv)
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var jv jsonValue
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return err
+	}
+
+	set := 0
+	for _, field := range []bool{jv.String != nil, jv.Number != nil, jv.Placeholder != nil} {
+		if field {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("value must have either a string, a number or a placeholder")
+	}
+	*v = Value{}
+
+	if jv.Placeholder != nil {
+		if !validName(*jv.Placeholder) {
+			return fmt.Errorf("invalid placeholder name %q", *jv.Placeholder)
+		}
+		*v = Value{Placeholder: jv.Placeholder, Token: jv.Token}
+		if v.Token == nil {
+			v.Token = &lexer.Token{Type: lexer.Placeholder, Literal: "$" + *jv.Placeholder}
+		}
+		return nil
+	}
+
+	if jv.Number != nil {
+		v.String = nil
+		v.Number = jv.Number
+		v.Token = jv.Token
+		if v.Token == nil {
+			literal := strconv.FormatFloat(*jv.Number, 'f', -1, 64)
+			v.Token = &lexer.Token{Type: lexer.Number, Literal: literal}
+		}
+		return nil
+	}
+
+	// keep the original quoting if the token still matches the value
+	if jv.Token != nil && jv.Token.Type == lexer.String {
+		if str, err := unquote(jv.Token.Literal); err == nil && str == *jv.String {
+			v.String = &jv.Token.Literal
+			v.Number = nil
+			v.Token = jv.Token
+			return nil
+		}
+	}
+
+	literal, ok := quote(*jv.String)
+	if !ok {
+		// like bound parameters, see Value
+		v.unquoted = jv.String
+	}
+	v.String = &literal
+	v.Number = nil
+	v.Token = &lexer.Token{Type: lexer.String, Literal: literal}
+	if jv.Token != nil {
+		v.Token.Line = jv.Token.Line
+		v.Token.Position = jv.Token.Position
+	}
+	return nil
+}
+
+func checkReference(ref *Reference) (*Reference, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if !validName(ref.Name) {
+		return nil, fmt.Errorf("invalid reference name %q", ref.Name)
+	}
+	if ref.Token == nil {
+		ref.Token = &lexer.Token{Type: lexer.Reference, Literal: "@" + ref.Name}
+	}
+	return ref, nil
+}
@@ -0,0 +1,301 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	src := `callsign =~ 'AFL\'s' and (alt > 1000.5 || arrival = "UUEE")`
+	expr := parseString(t, src)
+
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %v", err)
+	}
+
+	var decoded Expression[uint]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling %s: %v", data, err)
+	}
+
+	if decoded.String() != expr.String() {
+		t.Errorf("invalid decoded expression, got %s, expected %s", decoded.String(), expr.String())
+	}
+
+	// positions are kept
+	cond := decoded.Right.Left.Grouping.Expression.Left.Condition
+	if cond.Identifier.Token.Line != 1 || cond.Identifier.Token.Position != 27 {
+		t.Errorf("invalid identifier position %s", cond.Identifier.Token)
+	}
+	if *decoded.Left.Condition.Value.String != `'AFL\'s'` {
+		t.Errorf("original quoting is lost, got %s", *decoded.Left.Condition.Value.String)
+	}
+}
+
+func TestJSONDottedIdentifiers(t *testing.T) {
+	src := `flight_plan.arrival = "EGLL" and legs.0.fix != "x"`
+	data, err := json.Marshal(parseString(t, src))
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %v", err)
+	}
+
+	var decoded Expression[uint]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling %s: %v", data, err)
+	}
+	if result := formatExpr(t, &decoded, FormatOptions{}); result != src {
+		t.Errorf("invalid decoded expression, got %s, expected %s", result, src)
+	}
+	if name := decoded.Right.Left.Condition.Identifier.Name; name != "legs.0.fix" {
+		t.Errorf("invalid identifier name %s", name)
+	}
+}
+
+func TestJSONNegativeNumber(t *testing.T) {
+	data := `{"version": 2, "left": {"condition": {"identifier": {"name": "lon"}, "operator": {"symbol": "<"}, "value": {"number": -5.5}}}}`
+	var expr Expression[uint]
+	if err := json.Unmarshal([]byte(data), &expr); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %v", err)
+	}
+
+	src := formatExpr(t, &expr, FormatOptions{})
+	if parsed := parseString(t, src); *parsed.Left.Condition.Value.Number != -5.5 {
+		t.Errorf("formatted %s parses to %s", src, parsed)
+	}
+}
+
+func TestJSONWithoutTokens(t *testing.T) {
+	data := `{
+		"version": 1,
+		"left": {"condition": {
+			"identifier": {"name": "arrival"},
+			"operator": {"symbol": "="},
+			"value": {"string": "EG\"LL"}
+		}},
+		"operator": {"symbol": "or"},
+		"right": {"left": {"condition": {
+			"identifier": {"name": "alt"},
+			"operator": {"symbol": ">="},
+			"value": {"number": 100}
+		}}}
+	}`
+
+	var expr Expression[uint]
+	if err := json.Unmarshal([]byte(data), &expr); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %v", err)
+	}
+
+	exp := `arrival = "EG\"LL" or alt >= 100`
+	if result := formatExpr(t, &expr, FormatOptions{}); result != exp {
+		t.Errorf("invalid decoded expression, got %s, expected %s", result, exp)
+	}
+	if expr.Left.Condition.Identifier.Token.Line != 0 {
+		t.Errorf("synthesized token must have zero position")
+	}
+}
+
+func TestJSONVersion(t *testing.T) {
+	data, err := json.Marshal(parseString(t, `a = 1`))
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"version":2,`) {
+		t.Errorf("invalid version in %s", data)
+	}
+
+	// version 1 documents without placeholders and references are read
+	v1 := `{"version": 1, "left": {"grouping": {"expression": {"left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}}}}`
+	var expr Expression[uint]
+	if err := json.Unmarshal([]byte(v1), &expr); err != nil {
+		t.Errorf("unexpected error unmarshaling version 1: %v", err)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	cases := []string{
+		`{"left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 3, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"placeholder": "a"}}}}`,
+		`{"version": 1, "left": {"grouping": {"expression": {"left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}, "reference": {"name": "a"}}}}`,
+		`{"version": 1, "left": {}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "=="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}, "operator": {"symbol": "and"}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a b"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "or"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a..b"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": ".a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+	}
+
+	for i, data := range cases {
+		var expr Expression[uint]
+		if err := json.Unmarshal([]byte(data), &expr); err == nil {
+			t.Errorf("case %d should fail to unmarshal", i+1)
+		}
+	}
+}
+
+// validateSchema checks the document against the subset of JSON Schema
+// schema.json uses, unknown keywords are reported so the test can't pass
+// silently when the schema grows
+func validateSchema(root, schema map[string]any, doc any, path string) []string {
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	for keyword, arg := range schema {
+		switch keyword {
+		case "$schema", "$id", "$defs", "title", "description":
+		case "$ref":
+			name := strings.TrimPrefix(arg.(string), "#/$defs/")
+			def := root["$defs"].(map[string]any)[name].(map[string]any)
+			errs = append(errs, validateSchema(root, def, doc, path)...)
+		case "type":
+			var ok bool
+			switch arg {
+			case "object":
+				_, ok = doc.(map[string]any)
+			case "string":
+				_, ok = doc.(string)
+			case "number":
+				_, ok = doc.(float64)
+			case "integer":
+				f, isNumber := doc.(float64)
+				ok = isNumber && f == math.Trunc(f)
+			}
+			if !ok {
+				fail("expected %s, got %v", arg, doc)
+			}
+		case "const":
+			if !reflect.DeepEqual(doc, arg) {
+				fail("expected %v, got %v", arg, doc)
+			}
+		case "enum":
+			found := false
+			for _, v := range arg.([]any) {
+				found = found || reflect.DeepEqual(doc, v)
+			}
+			if !found {
+				fail("%v is not one of %v", doc, arg)
+			}
+		case "pattern":
+			if str, ok := doc.(string); ok && !regexp.MustCompile(arg.(string)).MatchString(str) {
+				fail("%q doesn't match %s", str, arg)
+			}
+		case "minLength":
+			if str, ok := doc.(string); ok && len(str) < int(arg.(float64)) {
+				fail("%q is too short", str)
+			}
+		case "minimum":
+			if f, ok := doc.(float64); ok && f < arg.(float64) {
+				fail("%v is less than %v", f, arg)
+			}
+		case "properties":
+			obj, _ := doc.(map[string]any)
+			for name, sub := range arg.(map[string]any) {
+				if v, found := obj[name]; found {
+					errs = append(errs, validateSchema(root, sub.(map[string]any), v, path+"."+name)...)
+				}
+			}
+		case "additionalProperties":
+			obj, _ := doc.(map[string]any)
+			props, _ := schema["properties"].(map[string]any)
+			for name := range obj {
+				if _, found := props[name]; !found && arg == false {
+					fail("unexpected property %s", name)
+				}
+			}
+		case "required":
+			obj, _ := doc.(map[string]any)
+			for _, name := range arg.([]any) {
+				if _, found := obj[name.(string)]; !found {
+					fail("missing property %s", name)
+				}
+			}
+		case "dependentRequired":
+			obj, _ := doc.(map[string]any)
+			for name, deps := range arg.(map[string]any) {
+				if _, found := obj[name]; !found {
+					continue
+				}
+				for _, dep := range deps.([]any) {
+					if _, found := obj[dep.(string)]; !found {
+						fail("%s requires %s", name, dep)
+					}
+				}
+			}
+		case "oneOf":
+			matched := 0
+			for _, sub := range arg.([]any) {
+				if len(validateSchema(root, sub.(map[string]any), doc, path)) == 0 {
+					matched++
+				}
+			}
+			if matched != 1 {
+				fail("matches %d schemas of oneOf", matched)
+			}
+		default:
+			fail("unsupported schema keyword %s", keyword)
+		}
+	}
+	return errs
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(JSONSchema), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	macros := testMacros(t, map[string]string{"heavy": `wtc = "H" or wtc = "J"`})
+	sources := []string{
+		`callsign =~ 'AFL\'s' and (alt > 1000.5 || arrival = "UUEE")`,
+		`arrival = :airport or alt <= $ceiling`,
+		`@heavy and ((a != 1))`,
+		`flight_plan.arrival = "EGLL" and legs.0.fix != "x"`,
+	}
+	for _, src := range sources {
+		expr, err := parseMacros(src, macros)
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", src, err)
+		}
+		data, err := json.Marshal(expr)
+		if err != nil {
+			t.Fatalf("unexpected error marshaling: %v", err)
+		}
+
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		for _, err := range validateSchema(schema, schema, doc, "$") {
+			t.Errorf("%s: %s", src, err)
+		}
+	}
+
+	invalid := []string{
+		`{"left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1, "string": "1"}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "=="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a"}, "operator": {"symbol": "="}, "value": {"number": 1}}}, "operator": {"symbol": "and"}}`,
+		`{"version": 1, "left": {"grouping": {"expression": {"left": {}}, "reference": {"name": "a b"}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a..b"}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+		`{"version": 1, "left": {"condition": {"identifier": {"name": "a."}, "operator": {"symbol": "="}, "value": {"number": 1}}}}`,
+	}
+	for i, data := range invalid {
+		var doc any
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if len(validateSchema(schema, schema, doc, "$")) == 0 {
+			t.Errorf("case %d must not validate", i+1)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+type (
+	// Reference records the macro a grouping was expanded from
+	Reference struct {
+		Name  string       `json:"name"`
+		Token *lexer.Token `json:"token,omitempty"`
+	}
+
+	// Macros is a registry of named filters other filters refer to as
+	// @name, it's safe for concurrent use
+	Macros struct {
+		mu   sync.RWMutex
+		defs map[string]string
+	}
+
+	// refFrame is a reference being expanded, in is the macro it was found
+	// in, empty for the filter itself
+	refFrame struct {
+		name  string
+		token *lexer.Token
+		in    string
+	}
+)
+
+// NewMacros makes an empty registry
+func NewMacros() *Macros {
+	return &Macros{defs: make(map[string]string)}
+}
+
+// Define adds or replaces a macro. The source is only checked for syntax
+// errors here, references to other macros are resolved when a filter
+// using the macro is parsed, so macros may be defined in any order.
+func (m *Macros) Define(name string, src string) error {
+	if !validName(name) {
+		return fmt.Errorf("invalid macro name %q", name)
+	}
+
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		return fmt.Errorf("in @%s: %w", name, err)
+	}
+	p := &parser[any]{tokens: tokens, syntaxOnly: true}
+	if _, err := p.parseAll(); err != nil {
+		return fmt.Errorf("in @%s: %w", name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defs[name] = src
+	return nil
+}
+
+// Lookup returns the source of the macro
+func (m *Macros) Lookup(name string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	src, found := m.defs[name]
+	return src, found
+}
+
+// Names returns the sorted names of the defined macros
+func (m *Macros) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.defs))
+	for name := range m.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseWithMacros parses the filter expanding every @name reference into
+// a grouping holding the parsed macro. The grouping keeps the reference, so
+// the filter can still be formatted with it. Positions of the expanded
+// conditions are relative to the macro source.
+func ParseWithMacros[T any](tokens *lexer.TokenFlow, macros *Macros) (*Expression[T], error) {
+	p := newParser[T](tokens)
+	p.macros = macros
+	return p.parseAll()
+}
+
+func where(in string) string {
+	if in == "" {
+		return "the filter"
+	}
+	return "@" + in
+}
+
+func (p *parser[T]) parseReference() (*Grouping[T], error) {
+	t := p.tokens.Current()
+	p.tokens.Advance()
+	ref := &Reference{Name: t.Literal[1:], Token: t}
+	if p.syntaxOnly {
+		return &Grouping[T]{Reference: ref}, nil
+	}
+
+	for i, frame := range p.refs {
+		if frame.name != ref.Name {
+			continue
+		}
+		chain := make([]string, 0, len(p.refs)-i+1)
+		for _, f := range p.refs[i:] {
+			chain = append(chain, "@"+f.name)
+		}
+		chain = append(chain, t.Literal)
+		return nil, fmt.Errorf(
+			"reference cycle %s: %s at line %d pos %d in %s and at line %d pos %d in %s",
+			strings.Join(chain, " -> "),
+			t.Literal,
+			frame.token.Line,
+			frame.token.Position,
+			where(frame.in),
+			t.Line,
+			t.Position,
+			where(p.macro),
+		)
+	}
+
+	var src string
+	found := false
+	if p.macros != nil {
+		src, found = p.macros.Lookup(ref.Name)
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown reference %s at line %d pos %d", t.Literal, t.Line, t.Position)
+	}
+
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		return nil, fmt.Errorf("in %s: %w", t.Literal, err)
+	}
+
+	refs := make([]refFrame, len(p.refs), len(p.refs)+1)
+	copy(refs, p.refs)
+	sub := &parser[T]{
+		tokens: tokens,
+		macros: p.macros,
+		refs:   append(refs, refFrame{ref.Name, t, p.macro}),
+		macro:  ref.Name,
+	}
+	expr, err := sub.parseAll()
+	if err != nil {
+		return nil, fmt.Errorf("in %s: %w", t.Literal, err)
+	}
+	return &Grouping[T]{Expression: expr, Reference: ref}, nil
+}
@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+func testMacros(t *testing.T, defs map[string]string) *Macros {
+	t.Helper()
+	macros := NewMacros()
+	for name, src := range defs {
+		if err := macros.Define(name, src); err != nil {
+			t.Fatalf("error defining %s: %v", name, err)
+		}
+	}
+	return macros
+}
+
+func parseMacros(src string, macros *Macros) (*Expression[uint], error) {
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWithMacros[uint](tokens, macros)
+}
+
+func TestMacros(t *testing.T) {
+	macros := testMacros(t, map[string]string{
+		"uk_arrivals": `arrival =~ "^EG"`,
+		"heavy":       `wtc = "H" or wtc = "J"`,
+		"uk_heavy":    `@uk_arrivals and @heavy`,
+	})
+
+	expr, err := parseMacros(`@uk_heavy and altitude < 10000`, macros)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		opts   FormatOptions
+		output string
+	}{
+		{FormatOptions{}, `(arrival =~ "^EG" and wtc = "H" or wtc = "J") and altitude < 10000`},
+		{FormatOptions{KeepReferences: true}, `@uk_heavy and altitude < 10000`},
+	}
+	for _, tc := range cases {
+		if result := formatExpr(t, expr, tc.opts); result != tc.output {
+			t.Errorf("got %s, expected %s", result, tc.output)
+		}
+	}
+
+	p, err := expr.Compile(func(c *Condition[uint]) (Matcher[uint], error) {
+		return func(uint) bool { return true }, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error compiling: %v", err)
+	}
+	if len(p.conds) != 4 {
+		t.Errorf("expanded program must have 4 conditions, got %d", len(p.conds))
+	}
+
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %v", err)
+	}
+	var decoded Expression[uint]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %v", err)
+	}
+	if result := formatExpr(t, &decoded, FormatOptions{KeepReferences: true}); result != cases[1].output {
+		t.Errorf("references are lost in JSON, got %s", result)
+	}
+}
+
+func TestFormatSourceWithMacros(t *testing.T) {
+	macros := testMacros(t, map[string]string{"heavy": `wtc = "H" or wtc = "J"`})
+	src := `@heavy   AND alt<1000`
+
+	cases := []struct {
+		opts   FormatOptions
+		output string
+	}{
+		{FormatOptions{Macros: macros}, `(wtc = "H" or wtc = "J") and alt < 1000`},
+		{FormatOptions{Macros: macros, KeepReferences: true}, `@heavy and alt < 1000`},
+		{FormatOptions{KeepReferences: true}, `@heavy and alt < 1000`},
+	}
+	for _, tc := range cases {
+		result, err := Format(src, tc.opts)
+		if err != nil || result != tc.output {
+			t.Errorf("got %s %v, expected %s", result, err, tc.output)
+		}
+	}
+
+	if _, err := Format(src, FormatOptions{}); err == nil || err.Error() != "unknown reference @heavy at line 1 pos 1" {
+		t.Errorf("got %v, expected the unknown reference error", err)
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	macros := testMacros(t, map[string]string{
+		"a":    `x = 1 or @b`,
+		"b":    `y = 2 and @a`,
+		"self": `@self`,
+		"bad":  `@missing`,
+	})
+
+	cases := map[string]string{
+		`@a`:              "in @a: in @b: reference cycle @a -> @b -> @a: @a at line 1 pos 1 in the filter and at line 1 pos 11 in @b",
+		`z = 1 and @self`: "in @self: reference cycle @self -> @self: @self at line 1 pos 11 in the filter and at line 1 pos 1 in @self",
+		`@bad`:            "in @bad: unknown reference @missing at line 1 pos 1",
+		`@nope or x = 1`:  "unknown reference @nope at line 1 pos 1",
+		`x = 1) or @self`: "unexpected token ) at line 1 pos 6",
+	}
+	for src, exp := range cases {
+		_, err := parseMacros(src, macros)
+		if err == nil || err.Error() != exp {
+			t.Errorf("%s got %v, expected %s", src, err, exp)
+		}
+	}
+
+	if _, err := Parse[uint](mustTokenize(t, `@a`)); err == nil || err.Error() != "unknown reference @a at line 1 pos 1" {
+		t.Errorf("references without macros must fail, got %v", err)
+	}
+
+	defs := map[string]string{
+		"1x": `x = 1`,
+		"c":  `x = 1)`,
+		"d":  `x = `,
+	}
+	for name, src := range defs {
+		if err := macros.Define(name, src); err == nil {
+			t.Errorf("defining %s as %s must fail", name, src)
+		}
+	}
+	if names := strings.Join(macros.Names(), " "); names != "a b bad self" {
+		t.Errorf("invalid definitions must not be registered, got %s", names)
+	}
+}
+
+func mustTokenize(t *testing.T, src string) *lexer.TokenFlow {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	return tokens
+}
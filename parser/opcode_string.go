@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=opcode -trimprefix=op"; DO NOT EDIT.
+
+package parser
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[opMatch-0]
+	_ = x[opJumpIfFalse-1]
+	_ = x[opJumpIfTrue-2]
+	_ = x[opMatchJumpIfFalse-3]
+	_ = x[opMatchJumpIfTrue-4]
+}
+
+const _opcode_name = "MatchJumpIfFalseJumpIfTrueMatchJumpIfFalseMatchJumpIfTrue"
+
+var _opcode_index = [...]uint8{0, 5, 16, 26, 42, 57}
+
+func (i opcode) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_opcode_index)-1 {
+		return "opcode(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _opcode_name[_opcode_index[idx]:_opcode_index[idx+1]]
+}
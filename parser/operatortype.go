@@ -41,4 +41,21 @@ var (
 		lexer.Greater:        Greater,
 		lexer.GreaterOrEqual: GreaterOrEqual,
 	}
+
+	// canonical spelling of operators used by the formatter
+	combOperatorLiterals = map[CombineOperatorType]string{
+		And: "and",
+		Or:  "or",
+	}
+
+	operatorLiterals = map[OperatorType]string{
+		Equals:         "=",
+		NotEquals:      "!=",
+		Matches:        "=~",
+		NotMatches:     "!~",
+		Less:           "<",
+		LessOrEqual:    "<=",
+		Greater:        ">",
+		GreaterOrEqual: ">=",
+	}
 )
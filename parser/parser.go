@@ -9,10 +9,19 @@ import (
 
 type parser[T any] struct {
 	tokens *lexer.TokenFlow
+
+	// macros expand references, without them references are errors
+	// unless only the syntax is checked
+	macros     *Macros
+	syntaxOnly bool
+	// refs are the references being expanded, macro is the name of the
+	// one being parsed, empty for the filter itself
+	refs  []refFrame
+	macro string
 }
 
 func newParser[T any](tokens *lexer.TokenFlow) *parser[T] {
-	return &parser[T]{tokens}
+	return &parser[T]{tokens: tokens}
 }
 
 func unexpected(token *lexer.Token) error {
@@ -70,7 +79,7 @@ func (p *parser[T]) parseGrouping() (*Grouping[T], error) {
 		return nil, err
 	}
 
-	return &Grouping[T]{expr}, nil
+	return &Grouping[T]{Expression: expr}, nil
 }
 
 func (p *parser[T]) parseExpression() (*Expression[T], error) {
@@ -91,6 +100,11 @@ func (p *parser[T]) parseExpression() (*Expression[T], error) {
 		if err != nil {
 			return nil, err
 		}
+	} else if t.Type == lexer.Reference {
+		expr.Left.Grouping, err = p.parseReference()
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, unexpected(t)
 	}
@@ -144,6 +158,9 @@ func (p *parser[T]) parseCondition() (*Condition[T], error) {
 			return nil, err
 		}
 		cond.Value = &Value{Number: &value, Token: t}
+	} else if t.Type == lexer.Placeholder {
+		name := t.Literal[1:]
+		cond.Value = &Value{Placeholder: &name, Token: t}
 	} else {
 		return nil, unexpected(t)
 	}
@@ -151,7 +168,22 @@ func (p *parser[T]) parseCondition() (*Condition[T], error) {
 	return cond, nil
 }
 
+// Parse parses the whole token flow, tokens left after the filter, like an
+// unbalanced closing brace, are errors
 func Parse[T any](tokens *lexer.TokenFlow) (*Expression[T], error) {
 	p := newParser[T](tokens)
-	return p.parseExpression()
+	return p.parseAll()
+}
+
+// parseAll parses the whole token flow, parseExpression stops at the first
+// unbalanced closing brace
+func (p *parser[T]) parseAll() (*Expression[T], error) {
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.tokens.Current(); t.Type != lexer.EOF {
+		return nil, unexpected(t)
+	}
+	return expr, nil
 }
@@ -101,3 +101,19 @@ func TestParseCondition4(t *testing.T) {
 		return
 	}
 }
+
+func TestParseTrailingTokens(t *testing.T) {
+	cases := map[string]string{
+		`a = 1) or b = 2`:    "unexpected token ) at line 1 pos 6",
+		`(a = 1)) and b = 2`: "unexpected token ) at line 1 pos 8",
+	}
+	for src, exp := range cases {
+		tokens, err := lexer.Tokenize(src, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse[string](tokens); err == nil || err.Error() != exp {
+			t.Errorf("%s got %v, expected %s", src, err, exp)
+		}
+	}
+}
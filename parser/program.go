@@ -0,0 +1,395 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+//go:generate stringer -type=opcode -trimprefix=op
+
+type (
+	opcode uint8
+
+	// instruction is a single VM step, matcher and target are only used
+	// by the opcodes that need them
+	instruction struct {
+		op      opcode
+		matcher uint32
+		target  uint32
+	}
+
+	// Program is an expression compiled to a flat list of instructions.
+	// The VM has a single boolean accumulator instead of a stack:
+	// conditions store their result in it and jumps skip the rest of an
+	// and/or chain once its result is known.
+	//
+	// A program never changes after compilation and is safe for concurrent
+	// use as long as its matchers are. The expression it's compiled from is
+	// left untouched, so one expression may be compiled any number of times
+	// with different callbacks.
+	Program[T any] struct {
+		expr     *Expression[T]
+		code     []instruction
+		matchers []Matcher[T]
+		// matchersE is only set for programs compiled with CompileE
+		matchersE []MatcherE[T]
+		cb        CompilationCallback[T]
+		cbE       CompilationCallbackE[T]
+		cbCost    CostedCompilationCallback[T]
+		// costs estimate the evaluation of every matcher for Reorder
+		costs []float64
+		// unbound counts the conditions with placeholders, their matchers
+		// are nil until the program is bound
+		unbound    int
+		conds      []*Condition[T]
+		index      map[*Condition[T]]int
+		root       *progNode
+		fields     FieldFunc[T]
+		filterOpts FilterOptions
+	}
+
+	// progNode is a condition, referenced by its matcher index, or an
+	// and/or of any number of children
+	progNode struct {
+		matcher  int
+		op       CombineOperatorType
+		children []*progNode
+	}
+)
+
+const (
+	// acc = matchers[matcher](model)
+	opMatch opcode = iota
+	// if !acc goto target
+	opJumpIfFalse
+	// if acc goto target
+	opJumpIfTrue
+	// acc = matchers[matcher](model); if !acc goto target
+	opMatchJumpIfFalse
+	// acc = matchers[matcher](model); if acc goto target
+	opMatchJumpIfTrue
+)
+
+// Compile builds a program getting a matcher for every condition from
+// the callback. Conditions with placeholders are left for Bind, the
+// callback only sees them once they have values.
+func (e *Expression[T]) Compile(cb CompilationCallback[T]) (*Program[T], error) {
+	return e.compile(&Program[T]{cb: cb})
+}
+
+// CompileWithCost builds a program like Compile does, the callback also
+// estimates the cost of every matcher, which Reorder uses. Compile and
+// CompileE estimate the costs with DefaultCost.
+func (e *Expression[T]) CompileWithCost(cb CostedCompilationCallback[T]) (*Program[T], error) {
+	return e.compile(&Program[T]{cbCost: cb})
+}
+
+// compile fills the program which only has its callback set
+func (e *Expression[T]) compile(p *Program[T]) (*Program[T], error) {
+	p.expr = e
+	p.index = make(map[*Condition[T]]int)
+	if p.cbE != nil {
+		p.matchersE = make([]MatcherE[T], 0)
+	}
+
+	root, err := p.build(e)
+	if err != nil {
+		return nil, err
+	}
+	p.root = root
+	p.generate()
+	return p, nil
+}
+
+// Expression returns the expression the program is compiled from
+func (p *Program[T]) Expression() *Expression[T] {
+	return p.expr
+}
+
+// build compiles the conditions and flattens every chain of the same
+// combine operator into a single node
+func (p *Program[T]) build(e *Expression[T]) (*progNode, error) {
+	var left *progNode
+
+	if cond := e.Left.Condition; cond != nil {
+		var m Matcher[T]
+		var mE MatcherE[T]
+		// the matcher of a placeholder is estimated again once bound
+		cost := DefaultCost(cond)
+		if cond.Value.IsPlaceholder() {
+			p.unbound++
+		} else {
+			var err error
+			m, mE, cost, err = p.matcher(cond)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		p.index[cond] = len(p.matchers)
+		left = &progNode{matcher: len(p.matchers)}
+		p.matchers = append(p.matchers, m)
+		if p.matchersE != nil {
+			p.matchersE = append(p.matchersE, mE)
+		}
+		p.conds = append(p.conds, cond)
+		p.costs = append(p.costs, cost)
+	} else {
+		var err error
+		left, err = p.build(e.Left.Grouping.Expression)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Right == nil {
+		return left, nil
+	}
+
+	if e.Operator.Type != And && e.Operator.Type != Or {
+		return nil, fmt.Errorf("unsupported combine operator %s", e.Operator.Type)
+	}
+
+	right, err := p.build(e.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &progNode{matcher: -1, op: e.Operator.Type, children: []*progNode{left}}
+	if left.matcher < 0 && left.op == n.op {
+		n.children = left.children
+	}
+	if right.matcher < 0 && right.op == n.op {
+		n.children = append(n.children, right.children...)
+	} else {
+		n.children = append(n.children, right)
+	}
+	return n, nil
+}
+
+// matcher compiles the condition with the callback the program was
+// compiled with and estimates its cost
+func (p *Program[T]) matcher(c *Condition[T]) (Matcher[T], MatcherE[T], float64, error) {
+	if p.cbCost != nil {
+		m, cost, err := p.cbCost(c)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if m == nil {
+			return nil, nil, 0, fmt.Errorf("no matcher for condition %s", c)
+		}
+		return m, nil, cost, nil
+	}
+
+	if p.cbE == nil {
+		m, err := p.cb(c)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if m == nil {
+			return nil, nil, 0, fmt.Errorf("no matcher for condition %s", c)
+		}
+		return m, nil, DefaultCost(c), nil
+	}
+
+	mE, err := p.cbE(c)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if mE == nil {
+		return nil, nil, 0, fmt.Errorf("no matcher for condition %s", c)
+	}
+	m := func(model T) bool {
+		ok, err := mE(context.Background(), model)
+		return err == nil && ok
+	}
+	return m, mE, DefaultCost(c), nil
+}
+
+// generate emits the code for the program tree
+func (p *Program[T]) generate() {
+	p.code = nil
+	p.emit(p.root)
+	p.threadJumps()
+	p.fuse()
+}
+
+func (p *Program[T]) emit(n *progNode) {
+	if n.matcher >= 0 {
+		p.code = append(p.code, instruction{op: opMatch, matcher: uint32(n.matcher)})
+		return
+	}
+
+	jumpOp := opJumpIfFalse
+	if n.op == Or {
+		jumpOp = opJumpIfTrue
+	}
+
+	jumps := make([]int, 0, len(n.children)-1)
+	for i, child := range n.children {
+		p.emit(child)
+		if i < len(n.children)-1 {
+			jumps = append(jumps, len(p.code))
+			p.code = append(p.code, instruction{op: jumpOp})
+		}
+	}
+
+	// the accumulator already holds the result of the whole chain
+	// if any of the jumps is taken
+	for _, jump := range jumps {
+		p.code[jump].target = uint32(len(p.code))
+	}
+}
+
+// threadJumps makes every jump go straight to the instruction that does
+// the work. The accumulator doesn't change across jumps, so a jump landing
+// on a jump of the same kind is taken again, and one landing on a jump of
+// the opposite kind falls through.
+func (p *Program[T]) threadJumps() {
+	for i := range p.code {
+		ins := &p.code[i]
+		if ins.op != opJumpIfFalse && ins.op != opJumpIfTrue {
+			continue
+		}
+		for int(ins.target) < len(p.code) {
+			next := p.code[ins.target]
+			if next.op == ins.op {
+				ins.target = next.target
+			} else if next.op == opJumpIfFalse || next.op == opJumpIfTrue {
+				ins.target++
+			} else {
+				break
+			}
+		}
+	}
+}
+
+// fuse merges every match followed by a jump into a single instruction.
+// After threading no jump targets another jump, so the merged jumps can
+// be dropped safely.
+func (p *Program[T]) fuse() {
+	remap := make([]uint32, len(p.code)+1)
+	code := make([]instruction, 0, len(p.code))
+
+	for i := 0; i < len(p.code); i++ {
+		remap[i] = uint32(len(code))
+		ins := p.code[i]
+
+		if ins.op == opMatch && i+1 < len(p.code) {
+			next := p.code[i+1]
+			switch next.op {
+			case opJumpIfFalse:
+				ins = instruction{op: opMatchJumpIfFalse, matcher: ins.matcher, target: next.target}
+				i++
+				remap[i] = uint32(len(code))
+			case opJumpIfTrue:
+				ins = instruction{op: opMatchJumpIfTrue, matcher: ins.matcher, target: next.target}
+				i++
+				remap[i] = uint32(len(code))
+			}
+		}
+		code = append(code, ins)
+	}
+	remap[len(p.code)] = uint32(len(code))
+
+	for i := range code {
+		if code[i].op != opMatch {
+			code[i].target = remap[code[i].target]
+		}
+	}
+	p.code = code
+}
+
+// Evaluate runs the program against the model, a nil program or one with
+// unbound placeholders never matches
+func (p *Program[T]) Evaluate(model T) bool {
+	if p == nil || p.unbound > 0 {
+		return false
+	}
+
+	acc := false
+	code := p.code
+
+	for pc := 0; pc < len(code); {
+		ins := &code[pc]
+		switch ins.op {
+		case opMatch:
+			acc = p.matchers[ins.matcher](model)
+			pc++
+		case opJumpIfFalse:
+			if !acc {
+				pc = int(ins.target)
+			} else {
+				pc++
+			}
+		case opJumpIfTrue:
+			if acc {
+				pc = int(ins.target)
+			} else {
+				pc++
+			}
+		case opMatchJumpIfFalse:
+			acc = p.matchers[ins.matcher](model)
+			if !acc {
+				pc = int(ins.target)
+			} else {
+				pc++
+			}
+		case opMatchJumpIfTrue:
+			acc = p.matchers[ins.matcher](model)
+			if acc {
+				pc = int(ins.target)
+			} else {
+				pc++
+			}
+		}
+	}
+
+	return acc
+}
+
+// evaluateTree walks the expression tree recursively, it's the reference
+// the VM is checked against
+func (p *Program[T]) evaluateTree(e *Expression[T], model T) bool {
+	var left bool
+
+	if e.Left.Condition != nil {
+		left = p.matchers[p.index[e.Left.Condition]](model)
+	} else {
+		left = p.evaluateTree(e.Left.Grouping.Expression, model)
+	}
+
+	if e.Right == nil {
+		return left
+	}
+
+	switch e.Operator.Type {
+	case And:
+		if !left {
+			return false
+		}
+	case Or:
+		if left {
+			return true
+		}
+	}
+
+	return p.evaluateTree(e.Right, model)
+}
+
+// String disassembles the program
+func (p *Program[T]) String() string {
+	var sb strings.Builder
+	for pc, ins := range p.code {
+		sb.WriteString(fmt.Sprintf("%04d %s", pc, ins.op))
+		if ins.op != opJumpIfFalse && ins.op != opJumpIfTrue {
+			sb.WriteString(fmt.Sprintf(" m%d", ins.matcher))
+		}
+		if ins.op != opMatch {
+			sb.WriteString(fmt.Sprintf(" -> %04d", ins.target))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProgramDisassemble(t *testing.T) {
+	expr := parseString(t, `(c0 = 1 or c1 = 1) and c2 = 1 and c3 = 1`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := strings.Join([]string{
+		"0000 MatchJumpIfTrue m0 -> 0002",
+		"0001 MatchJumpIfFalse m1 -> 0004",
+		"0002 MatchJumpIfFalse m2 -> 0004",
+		"0003 Match m3",
+		"",
+	}, "\n")
+	if p.String() != exp {
+		t.Errorf("invalid program, got\n%s\nexpected\n%s", p, exp)
+	}
+}
+
+func TestProgramNilMatcher(t *testing.T) {
+	expr := parseString(t, `c0 = 1`)
+	_, err := expr.Compile(func(*Condition[uint]) (Matcher[uint], error) { return nil, nil })
+	if err == nil {
+		t.Errorf("compilation with a nil matcher should fail")
+	}
+
+	var p *Program[uint]
+	if p.Evaluate(1) {
+		t.Errorf("nil program should never match")
+	}
+}
+
+func TestProgramCompileTwice(t *testing.T) {
+	expr := parseString(t, `c0 = 1 and c1 = 1`)
+	always := func(*Condition[uint]) (Matcher[uint], error) {
+		return func(uint) bool { return true }, nil
+	}
+
+	var wg sync.WaitGroup
+	programs := make([]*Program[uint], 8)
+	for i := range programs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cb := bitmaskCompiler
+			if i%2 == 0 {
+				cb = always
+			}
+			p, err := expr.Compile(cb)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			programs[i] = p
+		}(i)
+	}
+	wg.Wait()
+
+	for i, p := range programs {
+		if p.Evaluate(0) != (i%2 == 0) {
+			t.Errorf("program %d uses matchers of another compilation", i)
+		}
+	}
+}
+
+func TestProgramMatchesTree(t *testing.T) {
+	const vars = 6
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 2000; i++ {
+		src := randomExpression(r, vars, 4)
+		expr := parseString(t, src)
+		p, err := expr.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", src, err)
+		}
+
+		for model := uint(0); model < 1<<vars; model++ {
+			if p.Evaluate(model) != p.evaluateTree(expr, model) {
+				t.Fatalf("%s evaluates differently for %06b, program:\n%s", src, model, p)
+			}
+		}
+	}
+}
+
+const benchmarkFilter = `(c0 = 1 or c1 = 1 or c2 = 1) and (c3 = 1 or (c4 = 1 and c5 = 1)) and c6 = 1 or c7 = 1`
+
+func benchmarkProgram(b *testing.B) *Program[uint] {
+	expr, err := Parse[uint](getParser[uint](benchmarkFilter).tokens)
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return p
+}
+
+func benchmarkModels() []uint {
+	r := rand.New(rand.NewSource(3))
+	models := make([]uint, 2000)
+	for i := range models {
+		models[i] = uint(r.Intn(256))
+	}
+	return models
+}
+
+func BenchmarkEvaluateTree(b *testing.B) {
+	p := benchmarkProgram(b)
+	models := benchmarkModels()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, m := range models {
+			p.evaluateTree(p.expr, m)
+		}
+	}
+}
+
+func BenchmarkEvaluateProgram(b *testing.B) {
+	p := benchmarkProgram(b)
+	models := benchmarkModels()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, m := range models {
+			p.Evaluate(m)
+		}
+	}
+}
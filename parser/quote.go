@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unquote strips the quotes from a string literal as produced by the lexer.
+// The only escape sequence the lexer knows is a backslash followed by the
+// quote symbol, every other backslash is kept as is.
+func unquote(literal string) (string, error) {
+	if len(literal) < 2 {
+		return "", fmt.Errorf("invalid string literal %s", literal)
+	}
+
+	quoteSym := literal[0]
+	if (quoteSym != '"' && quoteSym != '\'') || literal[len(literal)-1] != quoteSym {
+		return "", fmt.Errorf("invalid string literal %s", literal)
+	}
+
+	content := literal[1 : len(literal)-1]
+	var sb strings.Builder
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\\' && i+1 < len(content) && content[i+1] == quoteSym {
+			i++
+		}
+		sb.WriteByte(content[i])
+	}
+	return sb.String(), nil
+}
+
+// quote makes a double quoted string literal the lexer reads back as str.
+// A string ending with a backslash can't be represented as the backslash
+// would escape the closing quote, ok is false in that case and the literal
+// only approximates the string.
+func quote(str string) (literal string, ok bool) {
+	literal = `"` + strings.ReplaceAll(str, `"`, `\"`) + `"`
+	return literal, !strings.HasSuffix(str, "\\")
+}
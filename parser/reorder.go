@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"math"
+	"sort"
+)
+
+type (
+	// CostFunc estimates how expensive a condition is to evaluate,
+	// relative to a plain comparison which costs 1
+	CostFunc[T any] func(c *Condition[T]) float64
+
+	// Selectivity maps conditions to the share of models they match
+	Selectivity[T any] map[*Condition[T]]float64
+)
+
+const (
+	comparisonCost = 1
+	regexpCost     = 20
+
+	// used for conditions without selectivity statistics
+	defaultSelectivity = 0.5
+)
+
+// DefaultCost considers regular expressions much more expensive than
+// comparisons
+func DefaultCost[T any](c *Condition[T]) float64 {
+	switch c.Operator.Type {
+	case Matches, NotMatches:
+		return regexpCost
+	default:
+		return comparisonCost
+	}
+}
+
+// Sample measures the selectivity of every condition of the program by
+// running all of its matchers against the models
+func (p *Program[T]) Sample(models []T) Selectivity[T] {
+	counts := make([]int, len(p.matchers))
+	for _, model := range models {
+		for i, m := range p.matchers {
+			if m != nil && m(model) {
+				counts[i]++
+			}
+		}
+	}
+
+	sel := make(Selectivity[T])
+	for idx, cond := range p.conds {
+		if len(models) > 0 && p.matchers[idx] != nil {
+			sel[cond] = float64(counts[idx]) / float64(len(models))
+		} else {
+			sel[cond] = defaultSelectivity
+		}
+	}
+	return sel
+}
+
+// Reorder returns a copy of the program where the operands of every
+// and/or chain are sorted to minimize the expected evaluation cost: cheap
+// operands likely to short-circuit the chain go first. Conditions are
+// assumed independent, a nil cost function means the costs estimated
+// at compilation, see CompileWithCost, and conditions missing from sel
+// are assumed to match half of the models.
+//
+// The result of the evaluation doesn't change as long as the matchers
+// have no side effects and don't rely on the order of evaluation, i.e.
+// a matcher must not assume a previous condition guards it.
+func (p *Program[T]) Reorder(cost CostFunc[T], sel Selectivity[T]) *Program[T] {
+	costs := make([]float64, len(p.matchers))
+	probs := make([]float64, len(p.matchers))
+	for idx, cond := range p.conds {
+		costs[idx] = p.costs[idx]
+		if cost != nil {
+			costs[idx] = cost(cond)
+		}
+		probs[idx] = defaultSelectivity
+		if s, found := sel[cond]; found {
+			probs[idx] = s
+		}
+	}
+
+	root, _, _ := reorderNode(p.root, costs, probs)
+	reordered := *p
+	reordered.root = root
+	reordered.generate()
+	return &reordered
+}
+
+// reorderNode returns a sorted copy of the node along with its expected
+// cost and the probability of it being true
+func reorderNode(n *progNode, costs []float64, probs []float64) (*progNode, float64, float64) {
+	if n.matcher >= 0 {
+		return n, costs[n.matcher], probs[n.matcher]
+	}
+
+	type operand struct {
+		node *progNode
+		cost float64
+		prob float64
+		rank float64
+	}
+
+	operands := make([]operand, len(n.children))
+	for i, child := range n.children {
+		node, cost, prob := reorderNode(child, costs, probs)
+		// the probability an operand short-circuits the chain
+		stop := prob
+		if n.op == And {
+			stop = 1 - prob
+		}
+		rank := math.Inf(1)
+		if stop > 0 {
+			rank = cost / stop
+		}
+		operands[i] = operand{node, cost, prob, rank}
+	}
+
+	sort.SliceStable(operands, func(i, j int) bool {
+		return operands[i].rank < operands[j].rank
+	})
+
+	sorted := &progNode{matcher: -1, op: n.op, children: make([]*progNode, len(operands))}
+	expected := 0.0
+	// the probability the evaluation reaches the current operand
+	reach := 1.0
+	for i, o := range operands {
+		sorted.children[i] = o.node
+		expected += reach * o.cost
+		if n.op == And {
+			reach *= o.prob
+		} else {
+			reach *= 1 - o.prob
+		}
+	}
+
+	// reach is now the probability no operand short-circuited
+	prob := reach
+	if n.op == Or {
+		prob = 1 - reach
+	}
+	return sorted, expected, prob
+}
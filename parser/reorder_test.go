@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReorder(t *testing.T) {
+	expr := parseString(t, `c0 =~ "x" and c1 = 1 and (c2 = 1 or c3 =~ "x")`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// c2 rarely matches so it's checked after the regexp, and the group
+	// ends up more expensive than the c0 regexp
+	sel := Selectivity[uint]{
+		expr.Right.Right.Left.Grouping.Expression.Left.Condition: 0.01,
+	}
+	cost := func(c *Condition[uint]) float64 {
+		if c.Identifier.Name == "c2" {
+			return 5
+		}
+		return DefaultCost(c)
+	}
+
+	exp := strings.Join([]string{
+		"0000 MatchJumpIfFalse m1 -> 0004",
+		"0001 MatchJumpIfFalse m0 -> 0004",
+		"0002 MatchJumpIfTrue m3 -> 0004",
+		"0003 Match m2",
+		"",
+	}, "\n")
+	if r := p.Reorder(cost, sel); r.String() != exp {
+		t.Errorf("invalid program, got\n%s\nexpected\n%s", r, exp)
+	}
+}
+
+func TestReorderCompiledCost(t *testing.T) {
+	// c0 is an expensive lookup the compiler knows about
+	compiler := func(c *Condition[uint]) (Matcher[uint], float64, error) {
+		m, err := bitmaskCompiler(c)
+		if c.Identifier.Name == "c0" {
+			return m, 50, err
+		}
+		return m, 1, err
+	}
+	expr := parseString(t, `c0 = 1 and c1 = $x`)
+	p, err := expr.CompileWithCost(compiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err = p.Bind(map[string]any{"x": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "0000 MatchJumpIfFalse m1 -> 0002\n0001 Match m0\n"
+	if r := p.Reorder(nil, nil); r.String() != exp {
+		t.Errorf("invalid program, got\n%s\nexpected\n%s", r, exp)
+	}
+	// an explicit cost function overrides the compiled costs
+	exp = "0000 MatchJumpIfFalse m0 -> 0002\n0001 Match m1\n"
+	if r := p.Reorder(DefaultCost[uint], nil); r.String() != exp {
+		t.Errorf("invalid program, got\n%s\nexpected\n%s", r, exp)
+	}
+}
+
+func TestReorderSample(t *testing.T) {
+	expr := parseString(t, `c0 = 1 and c1 = 1`)
+	p, err := expr.Compile(bitmaskCompiler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sel := p.Sample([]uint{0b01, 0b01, 0b11, 0b00})
+	if s := sel[expr.Left.Condition]; s != 0.75 {
+		t.Errorf("invalid selectivity of c0, got %v, expected 0.75", s)
+	}
+	if s := sel[expr.Right.Left.Condition]; s != 0.25 {
+		t.Errorf("invalid selectivity of c1, got %v, expected 0.25", s)
+	}
+
+	// c1 is more likely to short-circuit the chain
+	exp := "0000 MatchJumpIfFalse m1 -> 0002\n0001 Match m0\n"
+	if r := p.Reorder(nil, sel); r.String() != exp {
+		t.Errorf("invalid program, got\n%s\nexpected\n%s", r, exp)
+	}
+}
+
+func TestReorderMatchesTree(t *testing.T) {
+	const vars = 6
+	r := rand.New(rand.NewSource(4))
+
+	for i := 0; i < 1000; i++ {
+		src := randomExpression(r, vars, 4)
+		expr := parseString(t, src)
+		p, err := expr.Compile(bitmaskCompiler)
+		if err != nil {
+			t.Fatalf("error compiling %s: %v", src, err)
+		}
+
+		sel := make(Selectivity[uint])
+		for cond := range p.index {
+			sel[cond] = r.Float64()
+		}
+		reordered := p.Reorder(func(*Condition[uint]) float64 { return r.Float64() * 10 }, sel)
+
+		for model := uint(0); model < 1<<vars; model++ {
+			if reordered.Evaluate(model) != p.evaluateTree(expr, model) {
+				t.Fatalf("%s evaluates differently for %06b, program:\n%s", src, model, reordered)
+			}
+		}
+	}
+}
+
+// regexpCompiler matches callsigns, "prefix" conditions are cheap
+func regexpCompiler(c *Condition[string]) (Matcher[string], error) {
+	value := c.Value.MustGetUnquotedStringValue()
+	if c.Operator.Type == Matches {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	return func(model string) bool { return strings.HasPrefix(model, value) }, nil
+}
+
+func benchmarkRegexpProgram(b *testing.B) (*Program[string], []string) {
+	expr, err := Parse[string](getParser[string](`callsign =~ "^[A-Z]{3}[0-9]+[A-Z]?$" and prefix = "AFL"`).tokens)
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := expr.Compile(regexpCompiler)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	prefixes := []string{"AFL", "BAW", "DLH", "KLM", "SAS", "UAE", "AAL", "DAL"}
+	r := rand.New(rand.NewSource(5))
+	models := make([]string, 2000)
+	for i := range models {
+		models[i] = prefixes[r.Intn(len(prefixes))] + strings.Repeat("1", r.Intn(4)+1)
+	}
+	return p, models
+}
+
+func BenchmarkEvaluateRegexpFirst(b *testing.B) {
+	p, models := benchmarkRegexpProgram(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, m := range models {
+			p.Evaluate(m)
+		}
+	}
+}
+
+func BenchmarkEvaluateReordered(b *testing.B) {
+	p, models := benchmarkRegexpProgram(b)
+	p = p.Reorder(nil, p.Sample(models))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, m := range models {
+			p.Evaluate(m)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/vatsimnerd/lee/lexer"
+)
+
+// Span is a range of the filter source, the end points just past the last
+// character. Lines and positions start at 1 like the token ones.
+type Span struct {
+	Line        int `json:"line"`
+	Position    int `json:"position"`
+	EndLine     int `json:"endLine"`
+	EndPosition int `json:"endPosition"`
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("line %d pos %d to line %d pos %d", s.Line, s.Position, s.EndLine, s.EndPosition)
+}
+
+// IsZero reports whether the span is unknown
+func (s Span) IsZero() bool {
+	return s.Line == 0
+}
+
+// Span returns the source range of the condition from the identifier to
+// the end of the value. It's zero for conditions without tokens, i.e.
+// built in code or decoded from JSON without them.
+func (c *Condition[T]) Span() Span {
+	start, end := c.Identifier.Token, c.Value.Token
+	if start == nil || end == nil || start.Line == 0 || end.Line == 0 {
+		return Span{}
+	}
+	line, pos := tokenEnd(end)
+	return Span{Line: start.Line, Position: start.Position, EndLine: line, EndPosition: pos}
+}
+
+// tokenEnd returns the position just past the token, string literals may
+// span several lines
+func tokenEnd(t *lexer.Token) (int, int) {
+	line, pos := t.Line, t.Position
+	for _, r := range t.Literal {
+		if r == '\n' {
+			line++
+			pos = 1
+		} else {
+			pos++
+		}
+	}
+	return line, pos
+}
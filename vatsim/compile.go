@@ -0,0 +1,199 @@
+package vatsim
+
+import (
+	"strings"
+	"time"
+
+	"github.com/vatsimnerd/lee/match"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+// the short names of the VATSIM ids, the feed has the same tables
+var (
+	facilityNames = map[int]string{
+		0: "OBS", 1: "FSS", 2: "DEL", 3: "GND", 4: "TWR", 5: "APP", 6: "CTR",
+	}
+	ratingNames = map[int]string{
+		-1: "INAC", 0: "SUS", 1: "OBS", 2: "S1", 3: "S2", 4: "S3", 5: "C1",
+		6: "C2", 7: "C3", 8: "I1", 9: "I2", 10: "I3", 11: "SUP", 12: "ADM",
+	}
+	pilotRatingNames = map[int]string{
+		0: "NEW", 1: "PPL", 3: "IR", 7: "CMEL", 15: "ATPL", 31: "FI", 63: "FE",
+	}
+	militaryRatingNames = map[int]string{
+		0: "M0", 1: "M1", 3: "M2", 7: "M3", 15: "M4",
+	}
+)
+
+// name returns the short name of the id, nil for unknown ids so that no
+// condition but a negated one matches them
+func name(names map[int]string, id int) any {
+	if short, found := names[id]; found {
+		return short
+	}
+	return nil
+}
+
+// timestamp formats times as RFC 3339 in UTC, which orders like the times
+func timestamp(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// flightPlanAccessors exposes the flight plan fields under the flight_plan
+// prefix, all of them are nil without a flight plan
+func flightPlanAccessors[T any](accessors map[string]match.Accessor[T], get func(T) *FlightPlan) {
+	fields := map[string]func(fp *FlightPlan) any{
+		"flight_rules":         func(fp *FlightPlan) any { return fp.FlightRules },
+		"aircraft":             func(fp *FlightPlan) any { return fp.Aircraft },
+		"aircraft_faa":         func(fp *FlightPlan) any { return fp.AircraftFAA },
+		"aircraft_short":       func(fp *FlightPlan) any { return fp.AircraftShort },
+		"departure":            func(fp *FlightPlan) any { return fp.Departure },
+		"arrival":              func(fp *FlightPlan) any { return fp.Arrival },
+		"alternate":            func(fp *FlightPlan) any { return fp.Alternate },
+		"cruise_tas":           func(fp *FlightPlan) any { return fp.CruiseTAS },
+		"altitude":             func(fp *FlightPlan) any { return fp.Altitude },
+		"deptime":              func(fp *FlightPlan) any { return fp.DepTime },
+		"enroute_time":         func(fp *FlightPlan) any { return fp.EnrouteTime },
+		"fuel_time":            func(fp *FlightPlan) any { return fp.FuelTime },
+		"remarks":              func(fp *FlightPlan) any { return fp.Remarks },
+		"route":                func(fp *FlightPlan) any { return fp.Route },
+		"revision_id":          func(fp *FlightPlan) any { return fp.RevisionID },
+		"assigned_transponder": func(fp *FlightPlan) any { return fp.AssignedTransponder },
+	}
+	for field, value := range fields {
+		value := value
+		accessors["flight_plan."+field] = func(model T) any {
+			fp := get(model)
+			if fp == nil {
+				return nil
+			}
+			return value(fp)
+		}
+	}
+}
+
+// PilotAccessors returns the accessors of every pilot field named like in
+// the feed, flight plan fields are prefixed with flight_plan, i.e.
+// flight_plan.arrival. The *_short fields hold the short names of the
+// ratings, times are RFC 3339 strings in UTC.
+func PilotAccessors() map[string]match.Accessor[*Pilot] {
+	accessors := map[string]match.Accessor[*Pilot]{
+		"cid":                   func(p *Pilot) any { return p.CID },
+		"name":                  func(p *Pilot) any { return p.Name },
+		"callsign":              func(p *Pilot) any { return p.Callsign },
+		"server":                func(p *Pilot) any { return p.Server },
+		"pilot_rating":          func(p *Pilot) any { return p.PilotRating },
+		"pilot_rating_short":    func(p *Pilot) any { return name(pilotRatingNames, p.PilotRating) },
+		"military_rating":       func(p *Pilot) any { return p.MilitaryRating },
+		"military_rating_short": func(p *Pilot) any { return name(militaryRatingNames, p.MilitaryRating) },
+		"latitude":              func(p *Pilot) any { return p.Latitude },
+		"longitude":             func(p *Pilot) any { return p.Longitude },
+		"altitude":              func(p *Pilot) any { return p.Altitude },
+		"groundspeed":           func(p *Pilot) any { return p.Groundspeed },
+		"transponder":           func(p *Pilot) any { return p.Transponder },
+		"heading":               func(p *Pilot) any { return p.Heading },
+		"qnh_i_hg":              func(p *Pilot) any { return p.QNHInHg },
+		"qnh_mb":                func(p *Pilot) any { return p.QNHMb },
+		"logon_time":            func(p *Pilot) any { return timestamp(p.LogonTime) },
+		"last_updated":          func(p *Pilot) any { return timestamp(p.LastUpdated) },
+	}
+	flightPlanAccessors(accessors, func(p *Pilot) *FlightPlan { return p.FlightPlan })
+	return accessors
+}
+
+// PilotCompiler compiles filters over pilots, see PilotAccessors
+func PilotCompiler() parser.CompilationCallback[*Pilot] {
+	return match.Compiler(PilotAccessors())
+}
+
+// PilotFields reports pilot fields in explanations
+func PilotFields() parser.FieldFunc[*Pilot] {
+	return match.Fields(PilotAccessors())
+}
+
+// controllerAccessors are shared by controllers and ATIS
+func controllerAccessors[T any](get func(T) *Controller) map[string]match.Accessor[T] {
+	fields := map[string]func(c *Controller) any{
+		"cid":            func(c *Controller) any { return c.CID },
+		"name":           func(c *Controller) any { return c.Name },
+		"callsign":       func(c *Controller) any { return c.Callsign },
+		"frequency":      func(c *Controller) any { return c.Frequency },
+		"facility":       func(c *Controller) any { return c.Facility },
+		"facility_short": func(c *Controller) any { return name(facilityNames, c.Facility) },
+		"rating":         func(c *Controller) any { return c.Rating },
+		"rating_short":   func(c *Controller) any { return name(ratingNames, c.Rating) },
+		"server":         func(c *Controller) any { return c.Server },
+		"visual_range":   func(c *Controller) any { return c.VisualRange },
+		"text_atis":      func(c *Controller) any { return strings.Join(c.TextATIS, "\n") },
+		"last_updated":   func(c *Controller) any { return timestamp(c.LastUpdated) },
+		"logon_time":     func(c *Controller) any { return timestamp(c.LogonTime) },
+	}
+
+	accessors := make(map[string]match.Accessor[T], len(fields))
+	for field, value := range fields {
+		value := value
+		accessors[field] = func(model T) any { return value(get(model)) }
+	}
+	return accessors
+}
+
+// ControllerAccessors returns the accessors of every controller field
+// named like in the feed. facility_short and rating_short hold the short
+// names, i.e. TWR and C1, text_atis has the lines joined with newlines.
+func ControllerAccessors() map[string]match.Accessor[*Controller] {
+	return controllerAccessors(func(c *Controller) *Controller { return c })
+}
+
+// ControllerCompiler compiles filters over controllers, see
+// ControllerAccessors
+func ControllerCompiler() parser.CompilationCallback[*Controller] {
+	return match.Compiler(ControllerAccessors())
+}
+
+// ControllerFields reports controller fields in explanations
+func ControllerFields() parser.FieldFunc[*Controller] {
+	return match.Fields(ControllerAccessors())
+}
+
+// ATISAccessors returns the controller accessors and atis_code
+func ATISAccessors() map[string]match.Accessor[*ATIS] {
+	accessors := controllerAccessors(func(a *ATIS) *Controller { return &a.Controller })
+	accessors["atis_code"] = func(a *ATIS) any { return a.ATISCode }
+	return accessors
+}
+
+// ATISCompiler compiles filters over ATIS, see ATISAccessors
+func ATISCompiler() parser.CompilationCallback[*ATIS] {
+	return match.Compiler(ATISAccessors())
+}
+
+// ATISFields reports ATIS fields in explanations
+func ATISFields() parser.FieldFunc[*ATIS] {
+	return match.Fields(ATISAccessors())
+}
+
+// PrefileAccessors returns the accessors of every prefile field, flight
+// plan fields are prefixed like the pilot ones
+func PrefileAccessors() map[string]match.Accessor[*Prefile] {
+	accessors := map[string]match.Accessor[*Prefile]{
+		"cid":          func(p *Prefile) any { return p.CID },
+		"name":         func(p *Prefile) any { return p.Name },
+		"callsign":     func(p *Prefile) any { return p.Callsign },
+		"last_updated": func(p *Prefile) any { return timestamp(p.LastUpdated) },
+	}
+	flightPlanAccessors(accessors, func(p *Prefile) *FlightPlan { return p.FlightPlan })
+	return accessors
+}
+
+// PrefileCompiler compiles filters over prefiles, see PrefileAccessors
+func PrefileCompiler() parser.CompilationCallback[*Prefile] {
+	return match.Compiler(PrefileAccessors())
+}
+
+// PrefileFields reports prefile fields in explanations
+func PrefileFields() parser.FieldFunc[*Prefile] {
+	return match.Fields(PrefileAccessors())
+}
@@ -0,0 +1,117 @@
+package vatsim
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vatsimnerd/lee/lexer"
+	"github.com/vatsimnerd/lee/parser"
+)
+
+func compile[T any](t *testing.T, src string, cb parser.CompilationCallback[T]) *parser.Program[T] {
+	t.Helper()
+	tokens, err := lexer.Tokenize(src, true)
+	if err != nil {
+		t.Fatalf("error tokenizing %q: %v", src, err)
+	}
+	expr, err := parser.Parse[T](tokens)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", src, err)
+	}
+	p, err := expr.Compile(cb)
+	if err != nil {
+		t.Fatalf("error compiling %q: %v", src, err)
+	}
+	return p
+}
+
+func callsigns[T any](models []T, p *parser.Program[T], callsign func(T) string) string {
+	var result []string
+	for _, m := range p.Filter(models) {
+		result = append(result, callsign(m))
+	}
+	return strings.Join(result, " ")
+}
+
+func TestPilotCompiler(t *testing.T) {
+	feed := loadFeed(t)
+	cases := map[string]string{
+		`flight_plan.departure = "EGLL"`:                              "BAW117 AFR1145",
+		`altitude > 20000 and groundspeed >= 400`:                     "SBI1090 RCH451",
+		`flight_plan.arrival =~ "^K" or flight_plan.arrival = 1`:      "BAW117 RCH451",
+		`flight_plan.arrival != "KJFK"`:                               "AFR1145 N172SP SBI1090 RCH451",
+		`pilot_rating_short = "ATPL" or military_rating_short = "M2"`: "AFR1145 RCH451",
+		`latitude > 50 and longitude < 0`:                             "BAW117",
+		`logon_time < "2024-03-15T13:00:00Z"`:                         "SBI1090",
+		`flight_plan.altitude =~ "^FL"`:                               "RCH451",
+		`cid = 1000003 and transponder = "1200"`:                      "N172SP",
+	}
+
+	for src, exp := range cases {
+		p := compile(t, src, PilotCompiler())
+		if result := callsigns(feed.Pilots, p, func(p *Pilot) string { return p.Callsign }); result != exp {
+			t.Errorf("%s got %s, expected %s", src, result, exp)
+		}
+	}
+}
+
+func TestControllerCompilers(t *testing.T) {
+	feed := loadFeed(t)
+	cases := map[string]string{
+		`facility_short = "TWR" or facility = 6`:          "EGLL_N_TWR LON_S_CTR",
+		`rating_short = "S3" and server = "RUSSIA"`:       "UUEE_APP",
+		`text_atis =~ "(?i)tower"`:                        "EGLL_N_TWR",
+		`visual_range >= 300 and facility_short != "OBS"`: "LON_S_CTR",
+	}
+	for src, exp := range cases {
+		p := compile(t, src, ControllerCompiler())
+		if result := callsigns(feed.Controllers, p, func(c *Controller) string { return c.Callsign }); result != exp {
+			t.Errorf("%s got %s, expected %s", src, result, exp)
+		}
+	}
+
+	atis := compile(t, `atis_code = "K" and text_atis =~ "RUNWAY 27L"`, ATISCompiler())
+	if result := callsigns(feed.ATIS, atis, func(a *ATIS) string { return a.Callsign }); result != "EGLL_ATIS" {
+		t.Errorf("got %s, expected EGLL_ATIS", result)
+	}
+
+	prefile := compile(t, `flight_plan.arrival = "EGLL" and last_updated > "2024-03-15T14:00:00Z"`, PrefileCompiler())
+	if result := callsigns(feed.Prefiles, prefile, func(p *Prefile) string { return p.Callsign }); result != "DLH5KA" {
+		t.Errorf("got %s, expected DLH5KA", result)
+	}
+}
+
+// every exported field must be reachable from filters
+func TestAccessorsComplete(t *testing.T) {
+	cases := []struct {
+		typ       reflect.Type
+		accessors int
+	}{
+		{reflect.TypeOf(Pilot{}), len(PilotAccessors())},
+		{reflect.TypeOf(Controller{}), len(ControllerAccessors())},
+		{reflect.TypeOf(Prefile{}), len(PrefileAccessors())},
+	}
+	fp := reflect.TypeOf(FlightPlan{}).NumField()
+
+	expected := []int{
+		// flight plan is a prefix, ratings have short names
+		cases[0].typ.NumField() - 1 + fp + 2,
+		// facility and rating have short names
+		cases[1].typ.NumField() + 2,
+		cases[2].typ.NumField() - 1 + fp,
+	}
+	for i, tc := range cases {
+		if tc.accessors != expected[i] {
+			t.Errorf("%s has %d accessors, expected %d", tc.typ.Name(), tc.accessors, expected[i])
+		}
+	}
+	if len(ATISAccessors()) != len(ControllerAccessors())+1 {
+		t.Errorf("ATIS must have the controller accessors and atis_code")
+	}
+
+	fields := PilotFields()
+	if value, found := fields(&Pilot{}, "flight_plan.arrival"); !found || value != nil {
+		t.Errorf("flight plan fields of pilots without one must be nil, got %v", value)
+	}
+}
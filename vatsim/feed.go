@@ -0,0 +1,152 @@
+// Package vatsim has the models of the VATSIM v3 data feed and compilers
+// exposing their fields to filters, so a filter over the feed is a Parse
+// and a Compile away:
+//
+//	expr, err := parser.Parse[*vatsim.Pilot](tokens)
+//	...
+//	p, err := expr.Compile(vatsim.PilotCompiler())
+package vatsim
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type (
+	// Feed is the whole v3 data feed document
+	Feed struct {
+		General         General          `json:"general"`
+		Pilots          []*Pilot         `json:"pilots"`
+		Controllers     []*Controller    `json:"controllers"`
+		ATIS            []*ATIS          `json:"atis"`
+		Servers         []*Server        `json:"servers"`
+		Prefiles        []*Prefile       `json:"prefiles"`
+		Facilities      []Facility       `json:"facilities"`
+		Ratings         []Rating         `json:"ratings"`
+		PilotRatings    []PilotRating    `json:"pilot_ratings"`
+		MilitaryRatings []MilitaryRating `json:"military_ratings"`
+	}
+
+	General struct {
+		Version          int       `json:"version"`
+		Reload           int       `json:"reload"`
+		Update           string    `json:"update"`
+		UpdateTimestamp  time.Time `json:"update_timestamp"`
+		ConnectedClients int       `json:"connected_clients"`
+		UniqueUsers      int       `json:"unique_users"`
+	}
+
+	Pilot struct {
+		CID            int         `json:"cid"`
+		Name           string      `json:"name"`
+		Callsign       string      `json:"callsign"`
+		Server         string      `json:"server"`
+		PilotRating    int         `json:"pilot_rating"`
+		MilitaryRating int         `json:"military_rating"`
+		Latitude       float64     `json:"latitude"`
+		Longitude      float64     `json:"longitude"`
+		Altitude       int         `json:"altitude"`
+		Groundspeed    int         `json:"groundspeed"`
+		Transponder    string      `json:"transponder"`
+		Heading        int         `json:"heading"`
+		QNHInHg        float64     `json:"qnh_i_hg"`
+		QNHMb          int         `json:"qnh_mb"`
+		FlightPlan     *FlightPlan `json:"flight_plan"`
+		LogonTime      time.Time   `json:"logon_time"`
+		LastUpdated    time.Time   `json:"last_updated"`
+	}
+
+	// FlightPlan keeps the feed strings, even for the numeric fields which
+	// pilots may fill in freely
+	FlightPlan struct {
+		FlightRules         string `json:"flight_rules"`
+		Aircraft            string `json:"aircraft"`
+		AircraftFAA         string `json:"aircraft_faa"`
+		AircraftShort       string `json:"aircraft_short"`
+		Departure           string `json:"departure"`
+		Arrival             string `json:"arrival"`
+		Alternate           string `json:"alternate"`
+		CruiseTAS           string `json:"cruise_tas"`
+		Altitude            string `json:"altitude"`
+		DepTime             string `json:"deptime"`
+		EnrouteTime         string `json:"enroute_time"`
+		FuelTime            string `json:"fuel_time"`
+		Remarks             string `json:"remarks"`
+		Route               string `json:"route"`
+		RevisionID          int    `json:"revision_id"`
+		AssignedTransponder string `json:"assigned_transponder"`
+	}
+
+	Controller struct {
+		CID         int       `json:"cid"`
+		Name        string    `json:"name"`
+		Callsign    string    `json:"callsign"`
+		Frequency   string    `json:"frequency"`
+		Facility    int       `json:"facility"`
+		Rating      int       `json:"rating"`
+		Server      string    `json:"server"`
+		VisualRange int       `json:"visual_range"`
+		TextATIS    []string  `json:"text_atis"`
+		LastUpdated time.Time `json:"last_updated"`
+		LogonTime   time.Time `json:"logon_time"`
+	}
+
+	// ATIS is a controller connection broadcasting an ATIS
+	ATIS struct {
+		Controller
+		ATISCode string `json:"atis_code"`
+	}
+
+	Server struct {
+		Ident                    string `json:"ident"`
+		HostnameOrIP             string `json:"hostname_or_ip"`
+		Location                 string `json:"location"`
+		Name                     string `json:"name"`
+		ClientsConnectionAllowed int    `json:"clients_connection_allowed"`
+		ClientConnectionsAllowed bool   `json:"client_connections_allowed"`
+		IsSweatbox               bool   `json:"is_sweatbox"`
+	}
+
+	// Prefile is a flight plan filed before connecting
+	Prefile struct {
+		CID         int         `json:"cid"`
+		Name        string      `json:"name"`
+		Callsign    string      `json:"callsign"`
+		FlightPlan  *FlightPlan `json:"flight_plan"`
+		LastUpdated time.Time   `json:"last_updated"`
+	}
+
+	Facility struct {
+		ID    int    `json:"id"`
+		Short string `json:"short"`
+		Long  string `json:"long"`
+	}
+
+	Rating struct {
+		ID    int    `json:"id"`
+		Short string `json:"short"`
+		Long  string `json:"long"`
+	}
+
+	PilotRating struct {
+		ID        int    `json:"id"`
+		ShortName string `json:"short_name"`
+		LongName  string `json:"long_name"`
+	}
+
+	MilitaryRating struct {
+		ID        int    `json:"id"`
+		ShortName string `json:"short_name"`
+		LongName  string `json:"long_name"`
+	}
+)
+
+// Decode reads a feed document
+func Decode(r io.Reader) (*Feed, error) {
+	var feed Feed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
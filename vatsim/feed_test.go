@@ -0,0 +1,82 @@
+package vatsim
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadFeed(t testing.TB) *Feed {
+	t.Helper()
+	file, err := os.Open("testdata/feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	feed, err := Decode(file)
+	if err != nil {
+		t.Fatalf("error decoding the feed: %v", err)
+	}
+	return feed
+}
+
+func TestDecode(t *testing.T) {
+	feed := loadFeed(t)
+
+	if feed.General.Version != 3 || feed.General.UpdateTimestamp.Minute() != 30 {
+		t.Errorf("invalid general section %+v", feed.General)
+	}
+	counts := []struct {
+		name      string
+		got, want int
+	}{
+		{"pilots", len(feed.Pilots), 5},
+		{"controllers", len(feed.Controllers), 4},
+		{"atis", len(feed.ATIS), 2},
+		{"servers", len(feed.Servers), 2},
+		{"prefiles", len(feed.Prefiles), 1},
+	}
+	for _, c := range counts {
+		if c.got != c.want {
+			t.Errorf("got %d %s, expected %d", c.got, c.name, c.want)
+		}
+	}
+
+	if fp := feed.Pilots[0].FlightPlan; fp == nil || fp.Arrival != "KJFK" {
+		t.Errorf("invalid flight plan %+v", fp)
+	}
+	if feed.Pilots[2].FlightPlan != nil {
+		t.Errorf("null flight plan must be nil")
+	}
+	if atis := feed.ATIS[0]; atis.ATISCode != "K" || atis.Callsign != "EGLL_ATIS" {
+		t.Errorf("invalid atis %+v", atis)
+	}
+
+	if _, err := Decode(strings.NewReader(`{"pilots": {}}`)); err == nil {
+		t.Errorf("invalid feed must fail")
+	}
+}
+
+// the short name tables must agree with the ones the feed has
+func TestNames(t *testing.T) {
+	feed := loadFeed(t)
+
+	check := func(kind string, names map[int]string, id int, short string) {
+		if names[id] != short {
+			t.Errorf("%s %d is %s in the feed, got %s", kind, id, short, names[id])
+		}
+	}
+	for _, f := range feed.Facilities {
+		check("facility", facilityNames, f.ID, f.Short)
+	}
+	for _, r := range feed.Ratings {
+		check("rating", ratingNames, r.ID, r.Short)
+	}
+	for _, r := range feed.PilotRatings {
+		check("pilot rating", pilotRatingNames, r.ID, r.ShortName)
+	}
+	for _, r := range feed.MilitaryRatings {
+		check("military rating", militaryRatingNames, r.ID, r.ShortName)
+	}
+}